@@ -0,0 +1,143 @@
+package sticks
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// defaultAnalysisDepth is how many plies the analyzer searches without a
+// tablebase
+const defaultAnalysisDepth = 6
+
+// MoveAnalysis is the evaluation of one legal move for the player to move
+type MoveAnalysis struct {
+	Move     Move    `json:"move"`
+	Outcome  Outcome `json:"outcome"`  // for the player making the move; unknown if the search could not tell
+	Distance int     `json:"distance"` // plies until the game ends, counting this move; 0 unless won or lost
+	Score    int     `json:"score"`    // higher is better, comparable between the moves of one analysis
+	Reason   string  `json:"reason"`
+}
+
+// Analyzer ranks the current player's moves, exactly from a tablebase when one
+// was solved for the game's rules and by a depth limited search otherwise
+type Analyzer struct {
+	Tablebase *Tablebase // may be nil
+	Depth     int
+}
+
+func NewAnalyzer(tb *Tablebase) *Analyzer {
+	return &Analyzer{
+		Tablebase: tb,
+		Depth:     defaultAnalysisDepth,
+	}
+}
+
+// Analyze returns every legal move for the current player, best first
+func (a *Analyzer) Analyze(g *Game) ([]MoveAnalysis, error) {
+	game, moves, err := botPosition(g)
+	if err != nil {
+		return nil, err
+	}
+
+	me := game.CurrentTurn
+	exact := a.Tablebase != nil && game.Rules == a.Tablebase.Rules && len(game.Players) == 2
+
+	analyses := make([]MoveAnalysis, 0, len(moves))
+	for _, m := range moves {
+		child := game.clone()
+		if err := child.apply(m); err != nil {
+			return nil, err
+		}
+
+		var analysis MoveAnalysis
+		if exact {
+			analysis, err = a.probe(child, me, m)
+		} else {
+			analysis = a.search(child, me, m)
+		}
+		if err != nil {
+			return nil, err
+		}
+		analysis.Reason = reason(game, child, me, analysis)
+		analyses = append(analyses, analysis)
+	}
+
+	slices.SortStableFunc(analyses, func(x, y MoveAnalysis) int {
+		return cmp.Compare(y.Score, x.Score)
+	})
+	return analyses, nil
+}
+
+// probe looks up the position after m in the tablebase
+func (a *Analyzer) probe(child *Game, me int, m Move) (MoveAnalysis, error) {
+	p := child.position()
+	p.Turn = uint8(1 - me)
+	entry, ok := a.Tablebase.probe(p)
+	if !ok {
+		return MoveAnalysis{}, fmt.Errorf("position %v is missing from the tablebase", p)
+	}
+
+	analysis := MoveAnalysis{Move: m, Outcome: OutcomeDraw, Distance: 0, Score: 0, Reason: ""}
+	// the reply's loss is the mover's win
+	switch entry.Outcome {
+	case OutcomeLoss:
+		analysis.Outcome = OutcomeWin
+		analysis.Distance = entry.Distance + 1
+		analysis.Score = winScore - analysis.Distance
+	case OutcomeWin:
+		analysis.Outcome = OutcomeLoss
+		analysis.Distance = entry.Distance + 1
+		analysis.Score = -winScore + analysis.Distance
+	}
+	return analysis, nil
+}
+
+// search scores the position after m with minimax
+func (a *Analyzer) search(child *Game, me int, m Move) MoveAnalysis {
+	score := minimax(child, me, a.Depth-1, math.MinInt+1, math.MaxInt)
+	analysis := MoveAnalysis{Move: m, Outcome: OutcomeUnknown, Distance: 0, Score: score, Reason: ""}
+	// minimax adds the unused depth to decided scores
+	switch {
+	case score >= winScore:
+		analysis.Outcome = OutcomeWin
+		analysis.Distance = a.Depth - (score - winScore)
+	case score <= -winScore:
+		analysis.Outcome = OutcomeLoss
+		analysis.Distance = a.Depth - (-winScore - score)
+	}
+	return analysis
+}
+
+// reason explains a move's evaluation in a few words
+func reason(before, after *Game, me int, analysis MoveAnalysis) string {
+	m := analysis.Move
+	turns := (analysis.Distance + 1) / 2
+
+	switch {
+	case after.State == GameStateFinished && after.Winner != nil && after.Winner.Team == before.Players[me].Team:
+		return "wins the game on the spot"
+	case analysis.Outcome == OutcomeLoss && analysis.Distance <= 2:
+		return "lets your opponent win next move"
+	case analysis.Outcome == OutcomeWin:
+		return fmt.Sprintf("forces a win in %s", plural(turns, "move"))
+	case analysis.Outcome == OutcomeLoss:
+		return fmt.Sprintf("loses in %s against best play", plural(turns, "move"))
+	case m.Type == MoveTypeAttack && !after.Players[m.Target].GetHand(m.TargetLeft).Alive():
+		return fmt.Sprintf("kills player %d's %s hand", m.Target+1, handName(m.TargetLeft))
+	case analysis.Outcome == OutcomeDraw:
+		return "holds the draw"
+	case m.Type == MoveTypeSplit && !before.Players[me].GetHand(!m.WithLeft).Alive():
+		return fmt.Sprintf("brings your %s hand back", handName(!m.WithLeft))
+	default:
+		return "no forced result found"
+	}
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
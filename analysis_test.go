@@ -0,0 +1,72 @@
+package sticks
+
+import (
+	"testing"
+)
+
+func TestAnalyzer_Analyze(t *testing.T) {
+	tb, err := Solve(DefaultRuleset())
+	if err != nil {
+		t.Fatalf("Solve() error = %v", err)
+	}
+
+	for _, analyzer := range []*Analyzer{NewAnalyzer(tb), NewAnalyzer(nil)} {
+		name := "search"
+		if analyzer.Tablebase != nil {
+			name = "tablebase"
+		}
+		t.Run(name, func(t *testing.T) {
+			// 4,0 vs 0,1: only attacking the right hand with the left wins
+			game := newStartedGame(t, DefaultRuleset())
+			game.Players[0].LeftHand.Set(4)
+			game.Players[0].RightHand.Set(0)
+			game.Players[1].LeftHand.Set(0)
+			game.Players[1].RightHand.Set(1)
+
+			analyses, err := analyzer.Analyze(game)
+			if err != nil {
+				t.Fatalf("Analyzer.Analyze() error = %v", err)
+			}
+			if len(analyses) != len(game.LegalMoves()) {
+				t.Errorf("Analyzer.Analyze() has %d moves, want %d", len(analyses), len(game.LegalMoves()))
+			}
+			best := analyses[0]
+			if best.Move != AttackMove(1, true, false) || best.Outcome != OutcomeWin || best.Distance != 1 {
+				t.Errorf("Analyzer.Analyze() best = %+v, want a win in 1", best)
+			}
+			if best.Reason != "wins the game on the spot" {
+				t.Errorf("Analyzer.Analyze() reason = %q", best.Reason)
+			}
+			for i := 1; i < len(analyses); i++ {
+				if analyses[i].Score > analyses[i-1].Score {
+					t.Errorf("Analyzer.Analyze() is not sorted: %+v before %+v", analyses[i-1], analyses[i])
+				}
+			}
+		})
+	}
+}
+
+func TestAnalyzer_AgreesWithTablebase(t *testing.T) {
+	tb, err := Solve(DefaultRuleset())
+	if err != nil {
+		t.Fatalf("Solve() error = %v", err)
+	}
+
+	game := newStartedGame(t, DefaultRuleset())
+	entry, ok := tb.Lookup(game)
+	if !ok {
+		t.Fatalf("Tablebase.Lookup() missing the starting position")
+	}
+	analyses, err := NewAnalyzer(tb).Analyze(game)
+	if err != nil {
+		t.Fatalf("Analyzer.Analyze() error = %v", err)
+	}
+	if best := analyses[0]; best.Outcome != entry.Outcome {
+		t.Errorf("Analyzer.Analyze() best outcome = %v, want %v", best.Outcome, entry.Outcome)
+	}
+
+	game.Expire()
+	if _, err := NewAnalyzer(tb).Analyze(game); err == nil {
+		t.Errorf("Analyzer.Analyze() on a finished game should fail")
+	}
+}
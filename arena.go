@@ -0,0 +1,115 @@
+package sticks
+
+import (
+	"fmt"
+	"math"
+)
+
+// defaultArenaMaxMoves stops arena games that neither bot can finish
+const defaultArenaMaxMoves = 200
+
+// MatchResult tallies a match between two bots from the first bot's side
+type MatchResult struct {
+	Bots   [2]string `json:"bots"`
+	Games  int       `json:"games"`
+	Wins   int       `json:"wins"`
+	Draws  int       `json:"draws"`
+	Losses int       `json:"losses"`
+}
+
+// Score returns the first bot's points per game, counting draws as half
+func (r MatchResult) Score() float64 {
+	if r.Games == 0 {
+		return 0
+	}
+	return (float64(r.Wins) + float64(r.Draws)/2) / float64(r.Games)
+}
+
+// Arena plays bots against each other directly on Games, without the broker
+type Arena struct {
+	Rules    Ruleset
+	MaxMoves int // draw a game after this many moves, 0 for no limit
+}
+
+func NewArena(rules Ruleset) *Arena {
+	return &Arena{
+		Rules:    rules,
+		MaxMoves: defaultArenaMaxMoves,
+	}
+}
+
+// PlayMatch plays games between two bots, alternating who moves first. In
+// team games each bot plays for a whole team.
+func (a *Arena) PlayMatch(first, second Bot, games int) (MatchResult, error) {
+	if a.Rules.Players != 2 && !a.Rules.Teams {
+		return MatchResult{}, fmt.Errorf("arena games are between two bots or two teams, rules have %d players", a.Rules.Players)
+	}
+
+	result := MatchResult{Bots: [2]string{first.Name(), second.Name()}, Games: 0, Wins: 0, Draws: 0, Losses: 0}
+	for i := range games {
+		// the first bot plays for team 0 on even games and team 1 on odd ones,
+		// where without teams each player is a team of one
+		seat := i % 2
+		bots := [2]Bot{first, second}
+		if seat == 1 {
+			bots = [2]Bot{second, first}
+		}
+
+		game, err := a.PlayGame(bots)
+		if err != nil {
+			return result, fmt.Errorf("game %d: %w", i+1, err)
+		}
+		result.Games++
+		switch {
+		case game.State != GameStateFinished || game.Winner == nil:
+			result.Draws++
+		case game.Winner.Team == seat:
+			result.Wins++
+		default:
+			result.Losses++
+		}
+	}
+	return result, nil
+}
+
+// PlayGame plays a single game with bots[i] in seat i, or playing for team i
+// in team games, and returns it finished or drawn
+func (a *Arena) PlayGame(bots [2]Bot) (*Game, error) {
+	game := NewGame("arena", a.Rules)
+	game.MaxMoves = a.MaxMoves
+	for seat := range a.Rules.Players {
+		name := bots[a.Rules.team(seat)].Name()
+		if err := game.AddPlayer(NewPlayer(fmt.Sprintf("seat%d", seat+1), name)); err != nil {
+			return nil, err
+		}
+	}
+	if err := game.StartGame(); err != nil {
+		return nil, err
+	}
+
+	for game.State == GameStateInProgress {
+		bot := bots[game.Players[game.CurrentTurn].Team]
+		move, err := bot.ChooseMove(game)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", bot.Name(), err)
+		}
+		if err := game.Apply(move); err != nil {
+			return nil, fmt.Errorf("%s played %v: %w", bot.Name(), move, err)
+		}
+	}
+	return game, nil
+}
+
+// WilsonInterval returns the Wilson score interval for a proportion of
+// successes out of n trials, with z standard deviations (1.96 for 95%)
+func WilsonInterval(successes, n int, z float64) (float64, float64) {
+	if n == 0 {
+		return 0, 1
+	}
+	p := float64(successes) / float64(n)
+	nf := float64(n)
+	denom := 1 + z*z/nf
+	centre := (p + z*z/(2*nf)) / denom
+	margin := z * math.Sqrt(p*(1-p)/nf+z*z/(4*nf*nf)) / denom
+	return max(centre-margin, 0), min(centre+margin, 1)
+}
@@ -0,0 +1,60 @@
+package sticks
+
+import (
+	"math"
+	"testing"
+)
+
+func TestArena_PlayMatch(t *testing.T) {
+	arena := NewArena(DefaultRuleset())
+	result, err := arena.PlayMatch(NewGreedyBot(), NewRandomBot(), 20)
+	if err != nil {
+		t.Fatalf("Arena.PlayMatch() error = %v", err)
+	}
+	if result.Games != 20 || result.Wins+result.Draws+result.Losses != 20 {
+		t.Errorf("Arena.PlayMatch() = %+v, want 20 games tallied", result)
+	}
+	if result.Bots != [2]string{"greedy", "random"} {
+		t.Errorf("Arena.PlayMatch() bots = %v", result.Bots)
+	}
+	if score := result.Score(); score < 0 || score > 1 {
+		t.Errorf("MatchResult.Score() = %v, want between 0 and 1", score)
+	}
+
+	teams, err := NewArena(TeamRuleset()).PlayMatch(NewGreedyBot(), NewRandomBot(), 10)
+	if err != nil {
+		t.Fatalf("Arena.PlayMatch() with teams error = %v", err)
+	}
+	if teams.Wins+teams.Draws+teams.Losses != 10 {
+		t.Errorf("Arena.PlayMatch() with teams = %+v, want 10 games tallied", teams)
+	}
+
+	threePlayers := DefaultRuleset()
+	threePlayers.Players = 3
+	if _, err := NewArena(threePlayers).PlayMatch(NewRandomBot(), NewRandomBot(), 1); err == nil {
+		t.Errorf("Arena.PlayMatch() with three players should fail")
+	}
+}
+
+func TestWilsonInterval(t *testing.T) {
+	tests := []struct {
+		name      string
+		successes int
+		n         int
+		wantLo    float64
+		wantHi    float64
+	}{
+		{name: "no games", successes: 0, n: 0, wantLo: 0, wantHi: 1},
+		{name: "half", successes: 5, n: 10, wantLo: 0.2366, wantHi: 0.7634},
+		{name: "none", successes: 0, n: 40, wantLo: 0, wantHi: 0.0876},
+		{name: "all", successes: 40, n: 40, wantLo: 0.9124, wantHi: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lo, hi := WilsonInterval(tt.successes, tt.n, 1.96)
+			if math.Abs(lo-tt.wantLo) > 1e-3 || math.Abs(hi-tt.wantHi) > 1e-3 {
+				t.Errorf("WilsonInterval() = %.4f, %.4f, want %.4f, %.4f", lo, hi, tt.wantLo, tt.wantHi)
+			}
+		})
+	}
+}
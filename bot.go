@@ -0,0 +1,277 @@
+package sticks
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+)
+
+// Bot chooses moves for a computer controlled player
+type Bot interface {
+	// Name identifies the bot, e.g. in logs and as its player name
+	Name() string
+	// ChooseMove picks a move for the game's current player
+	ChooseMove(g *Game) (Move, error)
+}
+
+// BotDifficulty selects one of the built in bots
+type BotDifficulty string
+
+const (
+	BotDifficultyEasy    BotDifficulty = "easy"    // random moves
+	BotDifficultyMedium  BotDifficulty = "medium"  // greedy one move lookahead
+	BotDifficultyHard    BotDifficulty = "hard"    // depth limited minimax
+	BotDifficultyPerfect BotDifficulty = "perfect" // tablebase perfect play
+)
+
+// hardBotDepth is how many plies the hard bot searches
+const hardBotDepth = 6
+
+// NewBot returns the built in bot for a difficulty, playing under rules
+func NewBot(difficulty BotDifficulty, rules Ruleset) (Bot, error) {
+	switch difficulty {
+	case BotDifficultyEasy:
+		return NewRandomBot(), nil
+	case BotDifficultyMedium:
+		return NewGreedyBot(), nil
+	case BotDifficultyHard:
+		return NewMinimaxBot(hardBotDepth), nil
+	case BotDifficultyPerfect:
+		tb, err := Solve(rules)
+		if err != nil {
+			return nil, err
+		}
+		return NewPerfectBot(tb), nil
+	default:
+		return nil, fmt.Errorf("unknown bot difficulty: %q", difficulty)
+	}
+}
+
+// RandomBot plays a uniformly random legal move
+type RandomBot struct{}
+
+func NewRandomBot() *RandomBot {
+	return &RandomBot{}
+}
+
+func (b *RandomBot) Name() string {
+	return "random"
+}
+
+func (b *RandomBot) ChooseMove(g *Game) (Move, error) {
+	_, moves, err := botPosition(g)
+	if err != nil {
+		return Move{}, err
+	}
+	return moves[rand.IntN(len(moves))], nil
+}
+
+// GreedyBot plays the move that leaves the best looking position right now
+type GreedyBot struct{}
+
+func NewGreedyBot() *GreedyBot {
+	return &GreedyBot{}
+}
+
+func (b *GreedyBot) Name() string {
+	return "greedy"
+}
+
+func (b *GreedyBot) ChooseMove(g *Game) (Move, error) {
+	game, moves, err := botPosition(g)
+	if err != nil {
+		return Move{}, err
+	}
+
+	me := game.CurrentTurn
+	return bestMove(moves, func(m Move) int {
+		child := game.clone()
+		if err := child.apply(m); err != nil {
+			return math.MinInt
+		}
+		return evaluate(child, me)
+	}), nil
+}
+
+// MinimaxBot searches a fixed number of plies ahead with alpha-beta pruning
+type MinimaxBot struct {
+	Depth int
+}
+
+func NewMinimaxBot(depth int) *MinimaxBot {
+	return &MinimaxBot{
+		Depth: depth,
+	}
+}
+
+func (b *MinimaxBot) Name() string {
+	return fmt.Sprintf("minimax-%d", b.Depth)
+}
+
+func (b *MinimaxBot) ChooseMove(g *Game) (Move, error) {
+	game, moves, err := botPosition(g)
+	if err != nil {
+		return Move{}, err
+	}
+
+	me := game.CurrentTurn
+	return bestMove(moves, func(m Move) int {
+		child := game.clone()
+		if err := child.apply(m); err != nil {
+			return math.MinInt
+		}
+		return minimax(child, me, b.Depth-1, math.MinInt+1, math.MaxInt)
+	}), nil
+}
+
+// PerfectBot plays from a solved tablebase: the fastest win if there is one,
+// otherwise a draw, otherwise the slowest loss
+type PerfectBot struct {
+	Tablebase *Tablebase
+}
+
+func NewPerfectBot(tb *Tablebase) *PerfectBot {
+	return &PerfectBot{
+		Tablebase: tb,
+	}
+}
+
+func (b *PerfectBot) Name() string {
+	return "perfect"
+}
+
+func (b *PerfectBot) ChooseMove(g *Game) (Move, error) {
+	game, moves, err := botPosition(g)
+	if err != nil {
+		return Move{}, err
+	}
+	if game.Rules != b.Tablebase.Rules {
+		return Move{}, fmt.Errorf("tablebase was solved for different rules")
+	}
+
+	mover := game.CurrentTurn
+	return bestMove(moves, func(m Move) int {
+		child := game.clone()
+		if err := child.apply(m); err != nil {
+			return math.MinInt
+		}
+		p := child.position()
+		p.Turn = uint8(1 - mover)
+		entry, ok := b.Tablebase.probe(p)
+		if !ok {
+			return math.MinInt
+		}
+		// score from the mover's side: the reply's loss is our win
+		switch entry.Outcome {
+		case OutcomeLoss:
+			return winScore - entry.Distance
+		case OutcomeWin:
+			return -winScore + entry.Distance
+		default:
+			return 0
+		}
+	}), nil
+}
+
+// winScore outweighs any heuristic evaluation of an unfinished position
+const winScore = 1 << 20
+
+// botPosition takes a private copy of the game for a bot to search
+func botPosition(g *Game) (*Game, []Move, error) {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+
+	if g.State != GameStateInProgress {
+		return nil, nil, fmt.Errorf("game is not in progress")
+	}
+	moves := g.legalMoves()
+	if len(moves) == 0 {
+		return nil, nil, fmt.Errorf("no legal moves")
+	}
+	return g.clone(), moves, nil
+}
+
+// bestMove returns the highest scoring move, breaking ties at random
+func bestMove(moves []Move, score func(Move) int) Move {
+	var best []Move
+	bestScore := math.MinInt
+	for _, m := range moves {
+		s := score(m)
+		switch {
+		case s > bestScore:
+			best = []Move{m}
+			bestScore = s
+		case s == bestScore:
+			best = append(best, m)
+		}
+	}
+	return best[rand.IntN(len(best))]
+}
+
+// minimax scores the game for player me by alpha-beta search
+func minimax(g *Game, me, depth, alpha, beta int) int {
+	if g.State != GameStateInProgress || depth <= 0 {
+		// prefer quicker wins and slower losses
+		score := evaluate(g, me)
+		if score >= winScore {
+			return score + depth
+		}
+		if score <= -winScore {
+			return score - depth
+		}
+		return score
+	}
+
+	maximizing := g.currentPlayer().Team == g.Players[me].Team
+	best := math.MaxInt
+	if maximizing {
+		best = math.MinInt + 1
+	}
+	for _, m := range g.legalMoves() {
+		child := g.clone()
+		if err := child.apply(m); err != nil {
+			continue
+		}
+		score := minimax(child, me, depth-1, alpha, beta)
+		if maximizing {
+			best = max(best, score)
+			alpha = max(alpha, score)
+		} else {
+			best = min(best, score)
+			beta = min(beta, score)
+		}
+		if alpha >= beta {
+			break
+		}
+	}
+	return best
+}
+
+// evaluate scores a position from player me's side, treating every player on
+// another team as an enemy. Living hands are worth the most; among them, hands close
+// to the limit are easy to kill.
+func evaluate(g *Game, me int) int {
+	if g.State == GameStateFinished && g.Winner != nil {
+		if g.Winner.Team == g.Players[me].Team {
+			return winScore
+		}
+		return -winScore
+	}
+
+	score := 0
+	for _, p := range g.Players {
+		sign := 1
+		if p.Team != g.Players[me].Team {
+			sign = -1
+		}
+		for _, h := range []*Hand{p.LeftHand, p.RightHand} {
+			if !h.Alive() {
+				continue
+			}
+			score += sign * 100
+			// a hand one hit from dying is a liability
+			score -= sign * h.fingers * 10 / g.Rules.Fingers
+		}
+	}
+	return score
+}
@@ -0,0 +1,97 @@
+package sticks
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestBots_ChooseLegalMoves(t *testing.T) {
+	for _, difficulty := range []BotDifficulty{BotDifficultyEasy, BotDifficultyMedium, BotDifficultyHard, BotDifficultyPerfect} {
+		t.Run(string(difficulty), func(t *testing.T) {
+			bot, err := NewBot(difficulty, DefaultRuleset())
+			if err != nil {
+				t.Fatalf("NewBot() error = %v", err)
+			}
+
+			game := newStartedGame(t, DefaultRuleset())
+			for i := 0; i < 50 && game.State == GameStateInProgress; i++ {
+				move, err := bot.ChooseMove(game)
+				if err != nil {
+					t.Fatalf("%s.ChooseMove() error = %v", bot.Name(), err)
+				}
+				if !slices.Contains(game.LegalMoves(), move) {
+					t.Fatalf("%s.ChooseMove() = %v, not a legal move", bot.Name(), move)
+				}
+				if err := game.Apply(move); err != nil {
+					t.Fatalf("Game.Apply(%v) error = %v", move, err)
+				}
+			}
+		})
+	}
+
+	if _, err := NewBot("impossible", DefaultRuleset()); err == nil {
+		t.Errorf("NewBot() with an unknown difficulty should fail")
+	}
+}
+
+func TestBots_TakeImmediateWin(t *testing.T) {
+	tb, err := Solve(DefaultRuleset())
+	if err != nil {
+		t.Fatalf("Solve() error = %v", err)
+	}
+
+	for _, bot := range []Bot{NewGreedyBot(), NewMinimaxBot(4), NewPerfectBot(tb)} {
+		t.Run(bot.Name(), func(t *testing.T) {
+			// 4,0 vs 0,1: only attacking the right hand with the left wins
+			game := newStartedGame(t, DefaultRuleset())
+			game.Players[0].LeftHand.Set(4)
+			game.Players[0].RightHand.Set(0)
+			game.Players[1].LeftHand.Set(0)
+			game.Players[1].RightHand.Set(1)
+
+			move, err := bot.ChooseMove(game)
+			if err != nil {
+				t.Fatalf("%s.ChooseMove() error = %v", bot.Name(), err)
+			}
+			if want := AttackMove(1, true, false); move != want {
+				t.Errorf("%s.ChooseMove() = %v, want %v", bot.Name(), move, want)
+			}
+		})
+	}
+}
+
+func TestPerfectBot_NeverLosesToRandom(t *testing.T) {
+	tb, err := Solve(DefaultRuleset())
+	if err != nil {
+		t.Fatalf("Solve() error = %v", err)
+	}
+	perfect, random := NewPerfectBot(tb), NewRandomBot()
+
+	for i := range 20 {
+		game := newStartedGame(t, DefaultRuleset())
+		start, ok := tb.Lookup(game)
+		if !ok {
+			t.Fatalf("Tablebase.Lookup() missing the starting position")
+		}
+
+		for ply := 0; ply < 200 && game.State == GameStateInProgress; ply++ {
+			bot := Bot(random)
+			if game.GetTurn() == i%2 {
+				bot = perfect
+			}
+			move, err := bot.ChooseMove(game)
+			if err != nil {
+				t.Fatalf("%s.ChooseMove() error = %v", bot.Name(), err)
+			}
+			if err := game.Apply(move); err != nil {
+				t.Fatalf("Game.Apply(%v) error = %v", move, err)
+			}
+		}
+
+		perfectPlayer := game.Players[i%2]
+		// the perfect bot may only lose a position that was lost from the start
+		if game.State == GameStateFinished && game.Winner != perfectPlayer && !(i%2 == 0 && start.Outcome == OutcomeLoss) && !(i%2 == 1 && start.Outcome == OutcomeWin) {
+			t.Errorf("perfect bot lost game %d from a %v start", i, start.Outcome)
+		}
+	}
+}
@@ -2,8 +2,11 @@ package sticks
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"math"
+	"strings"
 	"sync"
 	"time"
 )
@@ -11,7 +14,36 @@ import (
 // MatchmakingRequest represents a player's request to join a game
 type MatchmakingRequest struct {
 	Player   *Player
+	Rules    Ruleset         // players are only matched with others asking for the same rules
+	Context  context.Context // the request is dropped from the queue once this is done
+	Rating   float64         // the player's rating, matched against players close to it
+	Region   string          // where the player is, for matchmakers that keep regions apart
 	Response chan *MatchmakingResponse
+	Status   chan QueueStatus // latest place in the queue, never blocks matchmaking
+	QueuedAt time.Time        // set when the request reaches the matchmaking worker
+}
+
+// QueueStatus is a waiting player's place in the matchmaking queue
+type QueueStatus struct {
+	Position      int           // 1 for the next player to be matched
+	Waiting       int           // players waiting for the same rules
+	EstimatedWait time.Duration // time left until a match, 0 if unknown
+}
+
+// QueueOptions are a player's preferences while waiting for a match
+type QueueOptions struct {
+	Region   string            // where the player is, for matchmakers that keep regions apart
+	OnStatus func(QueueStatus) // called with the player's place in the queue as it changes, may be nil
+}
+
+// ErrMatchmakingTimeout is returned when no match is found in time
+var ErrMatchmakingTimeout = errors.New("matchmaking timeout")
+
+// withdrawal asks the matchmaking worker to drop a request, closing done once
+// it is gone
+type withdrawal struct {
+	request *MatchmakingRequest
+	done    chan struct{}
 }
 
 // MatchmakingResponse contains the result of matchmaking
@@ -26,9 +58,16 @@ type GameBroker struct {
 	maxConcurrentGames int
 	matchmakingTimeout time.Duration
 	gameTimeout        time.Duration
+	rules              Ruleset       // rules for matchmade free for all games, including the number of seats
+	teamRules          Ruleset       // rules for matchmade team games
+	timeControl        *TimeControl  // clocks for matchmade games, nil for untimed
+	statusInterval     time.Duration // how often waiting players hear their place in the queue
+	matchmaker         Matchmaker    // only used by the matchmaking worker
+	ratings            *Ratings      // updated as matchmade games finish
 
 	// Matchmaking queue
-	queue chan *MatchmakingRequest
+	queue       chan *MatchmakingRequest
+	withdrawals chan withdrawal
 
 	// Active games tracking
 	activeGames map[string]*GameSession
@@ -50,17 +89,61 @@ type GameSession struct {
 	Cancel    context.CancelFunc
 	StartTime time.Time
 	Players   []*Player
+	Bot       Bot     // computer opponent, nil for games between people
+	BotPlayer *Player // seat the bot plays from
+	// Done is closed once the session is over, after the broker has ended
+	// the game if it ran out of time
+	Done chan struct{}
+	// Changed receives a value, without blocking, when the broker changes the
+	// game on its own: a flag falls, or the bot moves without a move to
+	// reply to because its turn came after a flag fell or a player left
+	Changed  chan struct{}
+	botMutex *sync.Mutex // one bot move at a time
+}
+
+// BrokerOption configures a GameBroker
+type BrokerOption func(*GameBroker)
+
+// WithMatchmaker sets how waiting players are matched. The broker takes over
+// m, which must not be shared with another broker.
+func WithMatchmaker(m Matchmaker) BrokerOption {
+	return func(gb *GameBroker) {
+		gb.matchmaker = m
+	}
+}
+
+// WithRules sets the rules of matchmade free for all games, including how
+// many seats they have
+func WithRules(rules Ruleset) BrokerOption {
+	return func(gb *GameBroker) {
+		gb.rules = rules
+	}
 }
 
-// NewGameBroker creates a new game broker
-func NewGameBroker(maxConcurrentGames int) *GameBroker {
+// WithTimeControl sets the clocks for matchmade games, nil for untimed games
+func WithTimeControl(tc *TimeControl) BrokerOption {
+	return func(gb *GameBroker) {
+		gb.timeControl = tc
+	}
+}
+
+// NewGameBroker creates a new game broker. Players are matched within a
+// widening rating window unless another matchmaker is given.
+func NewGameBroker(maxConcurrentGames int, options ...BrokerOption) *GameBroker {
 	ctx, cancel := context.WithCancel(context.Background())
 
-	return &GameBroker{
+	gb := &GameBroker{
 		maxConcurrentGames: maxConcurrentGames,
 		matchmakingTimeout: 30 * time.Second,
 		gameTimeout:        30 * time.Minute,
+		rules:              DefaultRuleset(),
+		teamRules:          TeamRuleset(),
+		timeControl:        &TimeControl{Base: 3 * time.Minute, Increment: 5 * time.Second, PerMove: 0},
+		statusInterval:     5 * time.Second,
+		matchmaker:         NewRatingMatchmaker(DefaultRatingWindow()),
+		ratings:            NewRatings(),
 		queue:              make(chan *MatchmakingRequest, 1000), // Buffered queue
+		withdrawals:        make(chan withdrawal),
 		activeGames:        make(map[string]*GameSession),
 		gameSemaphore:      make(chan struct{}, maxConcurrentGames),
 		ctx:                ctx,
@@ -68,6 +151,10 @@ func NewGameBroker(maxConcurrentGames int) *GameBroker {
 		gamesMutex:         new(sync.RWMutex),
 		wg:                 new(sync.WaitGroup),
 	}
+	for _, option := range options {
+		option(gb)
+	}
+	return gb
 }
 
 // Start begins the matchmaking broker
@@ -102,13 +189,44 @@ func (gb *GameBroker) Stop() {
 	log.Printf("GameBroker stopped")
 }
 
-// RequestGame adds a player to the matchmaking queue
-func (gb *GameBroker) RequestGame(player *Player) (*Game, error) {
-	responseChan := make(chan *MatchmakingResponse, 1)
+// RequestGame adds a player to the matchmaking queue and waits for a match.
+// Cancelling ctx takes the player out of the queue.
+func (gb *GameBroker) RequestGame(ctx context.Context, player *Player, options QueueOptions) (*Game, error) {
+	return gb.requestGame(ctx, player, gb.rules, options)
+}
+
+// RequestPartyGame adds a player to the matchmaking queue for a free for all
+// game with the given number of seats. The game starts once every seat is
+// filled.
+func (gb *GameBroker) RequestPartyGame(ctx context.Context, player *Player, players int, options QueueOptions) (*Game, error) {
+	rules := gb.rules
+	rules.Players = players
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+	return gb.requestGame(ctx, player, rules, options)
+}
+
+// RequestTeamGame adds a player to the matchmaking queue for a team game. The
+// game starts once all four seats are filled.
+func (gb *GameBroker) RequestTeamGame(ctx context.Context, player *Player, options QueueOptions) (*Game, error) {
+	return gb.requestGame(ctx, player, gb.teamRules, options)
+}
+
+// requestGame queues a player for a game under rules and waits for a match
+func (gb *GameBroker) requestGame(ctx context.Context, player *Player, rules Ruleset, options QueueOptions) (*Game, error) {
+	ctx, cancel := context.WithTimeout(ctx, gb.matchmakingTimeout)
+	defer cancel()
 
 	request := &MatchmakingRequest{
 		Player:   player,
-		Response: responseChan,
+		Rules:    rules,
+		Context:  ctx,
+		Rating:   gb.ratings.Get(player.ID).Value,
+		Region:   options.Region,
+		Response: make(chan *MatchmakingResponse, 1),
+		Status:   make(chan QueueStatus, 1),
+		QueuedAt: time.Time{},
 	}
 
 	// Try to add to queue with timeout
@@ -117,26 +235,63 @@ func (gb *GameBroker) RequestGame(player *Player) (*Game, error) {
 		// Successfully queued
 	case <-time.After(5 * time.Second):
 		return nil, fmt.Errorf("matchmaking queue is full")
+	case <-ctx.Done():
+		return nil, requestError(ctx)
 	case <-gb.ctx.Done():
 		return nil, fmt.Errorf("broker is shutting down")
 	}
 
 	// Wait for response
+	for {
+		select {
+		case response := <-request.Response:
+			return response.Game, response.Error
+		case status := <-request.Status:
+			if options.OnStatus != nil {
+				options.OnStatus(status)
+			}
+		case <-ctx.Done():
+			return gb.withdraw(request)
+		case <-gb.ctx.Done():
+			return nil, fmt.Errorf("broker is shutting down")
+		}
+	}
+}
+
+// withdraw takes a request whose context is done out of the queue. A match
+// made before the worker got to the withdrawal still stands.
+func (gb *GameBroker) withdraw(request *MatchmakingRequest) (*Game, error) {
+	w := withdrawal{request: request, done: make(chan struct{})}
 	select {
-	case response := <-responseChan:
-		return response.Game, response.Error
-	case <-time.After(gb.matchmakingTimeout):
-		return nil, fmt.Errorf("matchmaking timeout")
+	case gb.withdrawals <- w:
+		<-w.done
 	case <-gb.ctx.Done():
 		return nil, fmt.Errorf("broker is shutting down")
 	}
+
+	select {
+	case response := <-request.Response:
+		return response.Game, response.Error
+	default:
+		return nil, requestError(request.Context)
+	}
+}
+
+// requestError explains why a request's context ended
+func requestError(ctx context.Context) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return ErrMatchmakingTimeout
+	}
+	return ctx.Err()
 }
 
 // matchmakingWorker handles the core matchmaking logic
 func (gb *GameBroker) matchmakingWorker() {
 	defer gb.wg.Done()
 
-	var waitingPlayer *MatchmakingRequest
+	waits := waitTimes{}
+	ticker := time.NewTicker(gb.statusInterval)
+	defer ticker.Stop()
 
 	for {
 		select {
@@ -149,100 +304,252 @@ func (gb *GameBroker) matchmakingWorker() {
 				// Channel closed
 				return
 			}
+			if request.Context.Err() != nil {
+				// gave up before reaching the worker
+				continue
+			}
+
+			request.QueuedAt = time.Now()
+			gb.matchmaker.Enqueue(request)
+			log.Printf("Player %s waiting for match", request.Player.ID)
+			gb.match(waits, request.QueuedAt)
 
-			if waitingPlayer == nil {
-				// First player waiting
-				waitingPlayer = request
-				log.Printf("Player %s waiting for match", request.Player.ID)
-			} else {
-				// Second player arrived, create game
-				gb.createGame(waitingPlayer, request)
-				waitingPlayer = nil
+		case w := <-gb.withdrawals:
+			if gb.matchmaker.Cancel(w.request) {
+				log.Printf("Player %s left the queue", w.request.Player.ID)
+				gb.sendQueueStatus(waits, time.Now())
 			}
+			close(w.done)
+
+		case now := <-ticker.C:
+			// Matchmakers may match players just for having waited
+			gb.match(waits, now)
 
 		case <-gb.ctx.Done():
-			// Send cancellation to waiting player
-			if waitingPlayer != nil {
-				waitingPlayer.Response <- &MatchmakingResponse{
-					Error: fmt.Errorf("matchmaking cancelled"),
-					Game:  nil,
-				}
+			// Send cancellation to waiting players
+			for _, requests := range gb.matchmaker.Queues() {
+				gb.respondWithError(requests, fmt.Errorf("matchmaking cancelled"))
 			}
 			return
 		}
 	}
 }
 
-// createGame creates a new game between two players
-func (gb *GameBroker) createGame(player1Req, player2Req *MatchmakingRequest) {
+// match starts a game for every match the matchmaker has ready, then tells
+// everyone still waiting where they are in the queue
+func (gb *GameBroker) match(waits waitTimes, now time.Time) {
+	for _, m := range gb.matchmaker.Tick(now) {
+		waits.record(m, now)
+		gb.createGame(m)
+	}
+	gb.sendQueueStatus(waits, now)
+}
+
+// sendQueueStatus tells every waiting player where they are in their queue
+func (gb *GameBroker) sendQueueStatus(waits waitTimes, now time.Time) {
+	for _, requests := range gb.matchmaker.Queues() {
+		for i, request := range requests {
+			status := QueueStatus{
+				Position:      i + 1,
+				Waiting:       len(requests),
+				EstimatedWait: waits.estimate(request, now),
+			}
+			// replace a status the player has not picked up yet
+			select {
+			case <-request.Status:
+			default:
+			}
+			select {
+			case request.Status <- status:
+			default:
+			}
+		}
+	}
+}
+
+// waitTimes is the smoothed wait of recent matches under each ruleset
+type waitTimes map[Ruleset]time.Duration
+
+// record adds the waits of a match's players
+func (w waitTimes) record(m Match, now time.Time) {
+	for _, request := range m.Requests {
+		wait := now.Sub(request.QueuedAt)
+		if average, ok := w[m.Rules]; ok {
+			w[m.Rules] = average + (wait-average)/4
+		} else {
+			w[m.Rules] = wait
+		}
+	}
+}
+
+// estimate returns how much longer a request is likely to wait: the recent
+// average wait less the time already waited, or 0 if unknown
+func (w waitTimes) estimate(request *MatchmakingRequest, now time.Time) time.Duration {
+	average, ok := w[request.Rules]
+	if !ok {
+		return 0
+	}
+	return max(average-now.Sub(request.QueuedAt), 0)
+}
+
+// createGame creates a new game for a match, with any bot in the last seat
+func (gb *GameBroker) createGame(m Match) {
+	rules, requests := m.Rules, m.Requests
+
 	// Check if we can create a new game (concurrency limit)
 	select {
 	case gb.gameSemaphore <- struct{}{}:
 		// Got slot, proceed
 	default:
 		// No slots available
-		player1Req.Response <- &MatchmakingResponse{
-			Error: fmt.Errorf("server at capacity"),
-			Game:  nil,
-		}
-		player2Req.Response <- &MatchmakingResponse{
-			Error: fmt.Errorf("server at capacity"),
-			Game:  nil,
-		}
+		gb.respondWithError(requests, fmt.Errorf("server at capacity"))
 		return
 	}
 
 	// Create game
 	gameID := fmt.Sprintf("game_%d", time.Now().UnixNano())
-	game := NewGame(gameID)
-
-	// Add players to game
-	if err := game.AddPlayer(player1Req.Player); err != nil {
-		gb.respondWithError(player1Req, player2Req, err)
-		<-gb.gameSemaphore // Release slot
-		return
+	game := NewGame(gameID, rules)
+	game.TimeControl = gb.timeControl
+
+	// Add players to game, with the ratings they were matched on
+	var playerIDs []string
+	for _, request := range requests {
+		playerIDs = append(playerIDs, request.Player.ID)
+		request.Player.Rating = int(math.Round(request.Rating))
+		if err := game.AddPlayer(request.Player); err != nil {
+			gb.respondWithError(requests, err)
+			<-gb.gameSemaphore // Release slot
+			return
+		}
 	}
 
-	if err := game.AddPlayer(player2Req.Player); err != nil {
-		gb.respondWithError(player1Req, player2Req, err)
-		<-gb.gameSemaphore // Release slot
-		return
+	var botPlayer *Player
+	if m.Bot != nil {
+		botPlayer = NewPlayer(fmt.Sprintf("bot_%d", time.Now().UnixNano()), m.Bot.Name())
+		if err := game.AddPlayer(botPlayer); err != nil {
+			gb.respondWithError(requests, err)
+			<-gb.gameSemaphore // Release slot
+			return
+		}
+		playerIDs = append(playerIDs, m.Bot.Name()+" bot")
 	}
 
 	// Start game
 	if err := game.StartGame(); err != nil {
-		gb.respondWithError(player1Req, player2Req, err)
+		gb.respondWithError(requests, err)
 		<-gb.gameSemaphore // Release slot
 		return
 	}
 
-	// Create game session
+	gb.startSession(game, m.Bot, botPlayer)
+
+	// Respond to every player
+	for _, request := range requests {
+		request.Response <- &MatchmakingResponse{Game: game, Error: nil}
+	}
+
+	log.Printf("Created game %s between %s",
+		gameID, strings.Join(playerIDs, ", "))
+}
+
+// BotRules returns the rules of practice games against a bot: the rules of
+// matchmade games, with one seat for the player and one for the bot. Bots
+// for RequestBotGame should be built for these rules.
+func (gb *GameBroker) BotRules() Ruleset {
+	rules := gb.rules
+	rules.Players = 2
+	rules.Teams = false
+	rules.TeamTransfers = false
+	return rules
+}
+
+// RequestBotGame starts a practice game between a player and a computer
+// opponent. The player always moves first.
+func (gb *GameBroker) RequestBotGame(player *Player, bot Bot) (*Game, error) {
+	select {
+	case gb.gameSemaphore <- struct{}{}:
+		// Got slot, proceed
+	case <-gb.ctx.Done():
+		return nil, fmt.Errorf("broker is shutting down")
+	default:
+		return nil, fmt.Errorf("server at capacity")
+	}
+
+	gameID := fmt.Sprintf("game_%d", time.Now().UnixNano())
+	game := NewGame(gameID, gb.BotRules())
+	game.Practice = true
+	botPlayer := NewPlayer(fmt.Sprintf("bot_%d", time.Now().UnixNano()), bot.Name())
+
+	if err := errors.Join(game.AddPlayer(player), game.AddPlayer(botPlayer), game.StartGame()); err != nil {
+		<-gb.gameSemaphore // Release slot
+		return nil, err
+	}
+
+	gb.startSession(game, bot, botPlayer)
+
+	log.Printf("Created game %s between %s and %s bot", gameID, player.ID, bot.Name())
+	return game, nil
+}
+
+// startSession registers a started game and begins managing its lifecycle
+func (gb *GameBroker) startSession(game *Game, bot Bot, botPlayer *Player) *GameSession {
 	gameCtx, gameCancel := context.WithTimeout(gb.ctx, gb.gameTimeout)
 	session := &GameSession{
 		Game:      game,
 		Context:   gameCtx,
 		Cancel:    gameCancel,
 		StartTime: time.Now(),
-		Players:   []*Player{player1Req.Player, player2Req.Player},
+		Players:   game.Players,
+		Bot:       bot,
+		BotPlayer: botPlayer,
+		Done:      make(chan struct{}),
+		Changed:   make(chan struct{}, 1),
+		botMutex:  &sync.Mutex{},
 	}
 
 	// Register game session
 	gb.gamesMutex.Lock()
-	gb.activeGames[gameID] = session
+	gb.activeGames[game.ID] = session
 	gb.gamesMutex.Unlock()
 
 	// Start game management goroutine
 	go gb.manageGameSession(session)
 
-	// Respond to both players
-	player1Req.Response <- &MatchmakingResponse{Game: game, Error: nil}
-	player2Req.Response <- &MatchmakingResponse{
-		Game:  game,
-		Error: nil,
+	return session
+}
+
+// PlayBotTurn lets the session's bot move if it is the bot's turn, and
+// reports whether it moved
+func (s *GameSession) PlayBotTurn() (bool, error) {
+	if s.Bot == nil {
+		return false, nil
+	}
+	s.botMutex.Lock()
+	defer s.botMutex.Unlock()
+	if s.Game.IsOver() || s.Game.GetCurrentPlayer() != s.BotPlayer {
+		return false, nil
 	}
 
-	log.Printf("Created game %s between %s and %s",
-		gameID, player1Req.Player.ID, player2Req.Player.ID)
+	move, err := s.Bot.ChooseMove(s.Game)
+	if err != nil {
+		return false, err
+	}
+	if err := s.Game.Apply(move); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// DeclineBotDraw turns down a draw offered to the bot, which always plays on.
+// declined is false if there is no bot or no offer to decline.
+func (s *GameSession) DeclineBotDraw() (declined bool, err error) {
+	if s.Bot == nil || s.Game.Snapshot().DrawOfferedBy == "" {
+		return false, nil
+	}
+	if err := s.Game.DeclineDraw(s.BotPlayer.ID); err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
 // manageGameSession handles a single game's lifecycle
@@ -254,29 +561,61 @@ func (gb *GameBroker) manageGameSession(session *GameSession) {
 		gb.gamesMutex.Unlock()
 
 		session.Cancel()
+		close(session.Done)
 		<-gb.gameSemaphore // Release slot
 
 		log.Printf("Game %s ended after %v",
 			session.Game.ID, time.Since(session.StartTime))
 	}()
 
-	// Monitor game state
-	ticker := time.NewTicker(1 * time.Second)
+	// Monitor game state, often enough to flag a player close to when their
+	// clock runs out
+	ticker := time.NewTicker(250 * time.Millisecond)
 	defer ticker.Stop()
 
 	for {
 		select {
-		case <-ticker.C:
+		case now := <-ticker.C:
+			flagged := false
+			if mover := session.Game.GetCurrentPlayer(); session.Game.CheckFlag(now) {
+				log.Printf("Game %s lost on time by %s",
+					session.Game.ID, mover.ID)
+				flagged = true
+			}
+
+			// A bot whose turn came without a move to reply to, after a
+			// flag fell or a player left, moves on its own
+			moved, err := session.PlayBotTurn()
+			if err != nil {
+				log.Printf("Bot error in game %s: %v", session.Game.ID, err)
+			}
+			if flagged || moved {
+				select {
+				case session.Changed <- struct{}{}:
+				default:
+				}
+			}
+
 			// Check if game is finished
-			if session.Game.State == GameStateFinished {
-				log.Printf("Game %s finished, winner: %s",
-					session.Game.ID, session.Game.Winner.ID)
+			if session.Game.IsOver() {
+				// games with a bot are not rated
+				if session.Bot == nil && gb.ratings.Record(session.Game) {
+					log.Printf("Game %s rated", session.Game.ID)
+				}
+				// the hub may still be reading and writing the game
+				if snapshot := session.Game.Snapshot(); snapshot.Winner != "" {
+					log.Printf("Game %s finished, winner: %s",
+						session.Game.ID, snapshot.Winner)
+				} else {
+					log.Printf("Game %s drawn by %s",
+						session.Game.ID, snapshot.Result)
+				}
 				return
 			}
 
 		case <-session.Context.Done():
 			// Game timeout or cancellation
-			session.Game.State = GameStateFinished
+			session.Game.Expire()
 			log.Printf("Game %s timed out or cancelled", session.Game.ID)
 			return
 		}
@@ -347,14 +686,12 @@ func (gb *GameBroker) logMetrics() {
 
 // Helper methods
 
-func (gb *GameBroker) respondWithError(player1Req, player2Req *MatchmakingRequest, err error) {
-	player1Req.Response <- &MatchmakingResponse{
-		Error: err,
-		Game:  nil,
-	}
-	player2Req.Response <- &MatchmakingResponse{
-		Error: err,
-		Game:  nil,
+func (gb *GameBroker) respondWithError(requests []*MatchmakingRequest, err error) {
+	for _, request := range requests {
+		request.Response <- &MatchmakingResponse{
+			Error: err,
+			Game:  nil,
+		}
 	}
 }
 
@@ -375,6 +712,11 @@ func (gb *GameBroker) GetAvailableSlots() int {
 	return len(gb.gameSemaphore)
 }
 
+// Ratings returns the ratings of players in matchmade games
+func (gb *GameBroker) Ratings() *Ratings {
+	return gb.ratings
+}
+
 // GetGameSession returns a game session by ID
 func (gb *GameBroker) GetGameSession(gameID string) (*GameSession, bool) {
 	gb.gamesMutex.RLock()
@@ -396,7 +738,7 @@ func ExampleUsage() {
 
 	// Request games (these would typically be called from HTTP handlers)
 	go func() {
-		game, err := broker.RequestGame(player1)
+		game, err := broker.RequestGame(context.Background(), player1, QueueOptions{Region: "", OnStatus: nil})
 		if err != nil {
 			log.Printf("Player1 error: %v", err)
 			return
@@ -405,7 +747,7 @@ func ExampleUsage() {
 	}()
 
 	go func() {
-		game, err := broker.RequestGame(player2)
+		game, err := broker.RequestGame(context.Background(), player2, QueueOptions{Region: "", OnStatus: nil})
 		if err != nil {
 			log.Printf("Player2 error: %v", err)
 			return
@@ -0,0 +1,186 @@
+package sticks
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newTestBroker starts a broker that is stopped when the test ends
+func newTestBroker(t *testing.T) *GameBroker {
+	t.Helper()
+	broker := NewGameBroker(10)
+	broker.statusInterval = 10 * time.Millisecond
+	broker.Start()
+	t.Cleanup(broker.Stop)
+	return broker
+}
+
+func TestGameBroker_CancelLeavesQueue(t *testing.T) {
+	broker := newTestBroker(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	queued := make(chan QueueStatus, 10)
+	errs := make(chan error, 1)
+	go func() {
+		_, err := broker.RequestGame(ctx, NewPlayer("ghost", ""), QueueOptions{Region: "", OnStatus: func(s QueueStatus) { queued <- s }})
+		errs <- err
+	}()
+	if status := <-queued; status.Position != 1 || status.Waiting != 1 {
+		t.Errorf("queue status = %+v, want first of one", status)
+	}
+	cancel()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Fatalf("RequestGame() error = %v, want %v", err, context.Canceled)
+	}
+
+	// the next two players are matched with each other, not the ghost
+	games := make(chan *Game, 2)
+	for _, id := range []string{"alice", "bob"} {
+		go func() {
+			game, err := broker.RequestGame(context.Background(), NewPlayer(id, ""), QueueOptions{Region: "", OnStatus: nil})
+			if err != nil {
+				t.Errorf("RequestGame() error = %v", err)
+			}
+			games <- game
+		}()
+	}
+	for range 2 {
+		game := <-games
+		if game == nil {
+			continue
+		}
+		if game.PlayerIndex("ghost") >= 0 {
+			t.Errorf("game %s was matched with a player who left the queue", game.ID)
+		}
+	}
+}
+
+func TestGameBroker_Timeout(t *testing.T) {
+	broker := newTestBroker(t)
+	broker.matchmakingTimeout = 50 * time.Millisecond
+
+	if _, err := broker.RequestGame(context.Background(), NewPlayer("alice", ""), QueueOptions{Region: "", OnStatus: nil}); !errors.Is(err, ErrMatchmakingTimeout) {
+		t.Errorf("RequestGame() error = %v, want %v", err, ErrMatchmakingTimeout)
+	}
+}
+
+func TestGameBroker_BotBackfill(t *testing.T) {
+	broker := NewGameBroker(10, WithMatchmaker(NewBotBackfill(NewFIFOMatchmaker(), 0, BotDifficultyEasy)))
+	broker.statusInterval = 10 * time.Millisecond
+	broker.Start()
+	t.Cleanup(broker.Stop)
+
+	game, err := broker.RequestGame(context.Background(), NewPlayer("alice", ""), QueueOptions{Region: "", OnStatus: nil})
+	if err != nil {
+		t.Fatalf("RequestGame() error = %v", err)
+	}
+	session, ok := broker.GetGameSession(game.ID)
+	if !ok || session.Bot == nil || game.PlayerIndex(session.BotPlayer.ID) != 1 {
+		t.Errorf("game %s has no bot in the second seat", game.ID)
+	}
+}
+
+func TestGameBroker_BotGameRules(t *testing.T) {
+	rules := DefaultRuleset()
+	rules.Variant = RuleVariantRollover
+	rules.Players = 3
+	broker := NewGameBroker(10, WithRules(rules))
+	broker.Start()
+	t.Cleanup(broker.Stop)
+
+	want := rules
+	want.Players = 2
+	if got := broker.BotRules(); got != want {
+		t.Fatalf("BotRules() = %+v, want %+v", got, want)
+	}
+	bot, err := NewBot(BotDifficultyEasy, broker.BotRules())
+	if err != nil {
+		t.Fatalf("NewBot() error = %v", err)
+	}
+	game, err := broker.RequestBotGame(NewPlayer("alice", ""), bot)
+	if err != nil {
+		t.Fatalf("RequestBotGame() error = %v", err)
+	}
+	if game.Rules != want {
+		t.Errorf("bot game rules = %+v, want %+v", game.Rules, want)
+	}
+}
+
+func TestGameBroker_BackfilledBotMovesAfterFlag(t *testing.T) {
+	rules := DefaultRuleset()
+	rules.Players = 3
+	tc := &TimeControl{Base: 500 * time.Millisecond, Increment: 0, PerMove: 0}
+	broker := NewGameBroker(10,
+		WithMatchmaker(NewBotBackfill(NewFIFOMatchmaker(), 0, BotDifficultyEasy)),
+		WithRules(rules),
+		WithTimeControl(tc))
+	broker.statusInterval = 10 * time.Millisecond
+	broker.Start()
+	t.Cleanup(broker.Stop)
+
+	games := make(chan *Game, 2)
+	for _, id := range []string{"alice", "bob"} {
+		go func() {
+			game, err := broker.RequestGame(context.Background(), NewPlayer(id, ""), QueueOptions{Region: "", OnStatus: nil})
+			if err != nil {
+				t.Errorf("RequestGame() error = %v", err)
+			}
+			games <- game
+		}()
+	}
+	game := <-games
+	if other := <-games; game == nil || other != game {
+		t.Fatalf("players were not matched into one game")
+	}
+	session, ok := broker.GetGameSession(game.ID)
+	if !ok || session.Bot == nil || game.PlayerIndex(session.BotPlayer.ID) != 2 {
+		t.Fatalf("game %s has no bot in the last seat", game.ID)
+	}
+
+	// the first player moves and the second runs out of time, so the bot's
+	// turn comes without a move for it to reply to
+	if err := game.Apply(AttackMove(1, true, true)); err != nil {
+		t.Fatalf("Game.Apply() error = %v", err)
+	}
+	deadline := time.After(2 * time.Second)
+	for len(game.MoveHistory()) < 2 {
+		select {
+		case <-session.Changed:
+		case <-deadline:
+			t.Fatalf("the bot did not move after the second player flagged: %+v", game.Snapshot())
+		}
+	}
+	if mover := game.MoveHistory()[1].PlayerID; mover != session.BotPlayer.ID {
+		t.Errorf("second move played by %s, want the bot", mover)
+	}
+}
+
+func TestGameBroker_PartyGame(t *testing.T) {
+	broker := newTestBroker(t)
+
+	games := make(chan *Game, 3)
+	for _, id := range []string{"alice", "bob", "carol"} {
+		go func() {
+			game, err := broker.RequestPartyGame(context.Background(), NewPlayer(id, ""), 3, QueueOptions{Region: "", OnStatus: nil})
+			if err != nil {
+				t.Errorf("RequestPartyGame() error = %v", err)
+			}
+			games <- game
+		}()
+	}
+	first := <-games
+	for range 2 {
+		if game := <-games; game != first {
+			t.Fatalf("players were matched into different games")
+		}
+	}
+	if first == nil || len(first.Players) != 3 || first.Rules.Players != 3 {
+		t.Fatalf("party game = %v, want three seats filled", first)
+	}
+
+	if _, err := broker.RequestPartyGame(context.Background(), NewPlayer("dave", ""), MaxPlayers+1, QueueOptions{Region: "", OnStatus: nil}); err == nil {
+		t.Errorf("RequestPartyGame() with too many seats should fail")
+	}
+}
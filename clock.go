@@ -0,0 +1,125 @@
+package sticks
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrFlagged is returned for a move made after the mover's time ran out. The
+// move is not played and the mover is out of the game.
+var ErrFlagged = errors.New("out of time")
+
+// TimeControl configures the players' clocks. Either give every player a
+// Base amount of time topped up by Increment after each move, or a fixed
+// PerMove allowance that resets every turn.
+type TimeControl struct {
+	Base      time.Duration `json:"base"`
+	Increment time.Duration `json:"increment"`
+	PerMove   time.Duration `json:"perMove"`
+}
+
+// Validate checks that the time control gives players time to move
+func (tc TimeControl) Validate() error {
+	if tc.Base < 0 || tc.Increment < 0 || tc.PerMove < 0 {
+		return fmt.Errorf("time control durations cannot be negative")
+	}
+	if tc.Base == 0 && tc.PerMove == 0 {
+		return fmt.Errorf("time control needs a base time or a per move time")
+	}
+	return nil
+}
+
+// initial returns the time on each clock when the game starts
+func (tc TimeControl) initial() time.Duration {
+	if tc.PerMove > 0 {
+		return tc.PerMove
+	}
+	return tc.Base
+}
+
+// Clock is the time a player has left
+type Clock struct {
+	Remaining time.Duration `json:"remaining"`
+	Running   bool          `json:"running"`
+}
+
+// RemainingTime returns the time left on a player's clock at now, counting the
+// turn in progress. Untimed games report zero.
+func (g *Game) RemainingTime(turn int, now time.Time) time.Duration {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+	return g.remaining(turn, now)
+}
+
+// CheckFlag knocks the player to move out of the game if they have run out of
+// time, and reports whether they had. With two players, the opponent wins.
+func (g *Game) CheckFlag(now time.Time) bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.checkFlag(now)
+}
+
+// startClocks sets every clock to its starting time and starts the first
+// player's without locking
+func (g *Game) startClocks(now time.Time) {
+	if g.TimeControl == nil {
+		return
+	}
+	g.Clocks = make([]Clock, len(g.Players))
+	for i := range g.Clocks {
+		g.Clocks[i] = Clock{Remaining: g.TimeControl.initial(), Running: i == g.CurrentTurn}
+	}
+	g.turnStarted = now
+}
+
+// remaining returns the time left on a clock without locking
+func (g *Game) remaining(turn int, now time.Time) time.Duration {
+	if g.TimeControl == nil || turn < 0 || turn >= len(g.Clocks) {
+		return 0
+	}
+	clock := g.Clocks[turn]
+	if clock.Running {
+		return clock.Remaining - now.Sub(g.turnStarted)
+	}
+	return clock.Remaining
+}
+
+// checkFlag eliminates a player on time without locking
+func (g *Game) checkFlag(now time.Time) bool {
+	if g.TimeControl == nil || g.State != GameStateInProgress {
+		return false
+	}
+	if g.remaining(g.CurrentTurn, now) > 0 {
+		return false
+	}
+
+	g.Clocks[g.CurrentTurn] = Clock{Remaining: 0, Running: false}
+	g.eliminate(g.CurrentTurn, ResultFlag)
+	if g.State == GameStateInProgress {
+		g.Clocks[g.CurrentTurn].Running = true
+		g.turnStarted = now
+	}
+	return true
+}
+
+// pressClock charges the mover for the turn they just finished and starts the
+// next player's clock without locking
+func (g *Game) pressClock(mover int, now time.Time) {
+	if g.TimeControl == nil {
+		return
+	}
+
+	clock := &g.Clocks[mover]
+	clock.Remaining -= now.Sub(g.turnStarted)
+	clock.Remaining += g.TimeControl.Increment
+	if g.TimeControl.PerMove > 0 {
+		clock.Remaining = g.TimeControl.PerMove
+	}
+	clock.Running = false
+
+	if g.State == GameStateInProgress {
+		g.Clocks[g.CurrentTurn].Running = true
+	}
+	g.turnStarted = now
+}
@@ -0,0 +1,78 @@
+package sticks
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTimedGame(t *testing.T, tc TimeControl) *Game {
+	t.Helper()
+	game := NewGame("timed", DefaultRuleset())
+	game.TimeControl = &tc
+	if err := errors.Join(game.AddPlayer(NewPlayer("player 1", "")), game.AddPlayer(NewPlayer("player 2", "")), game.StartGame()); err != nil {
+		t.Fatalf("starting game error = %v", err)
+	}
+	return game
+}
+
+func TestGame_ClockIncrement(t *testing.T) {
+	game := newTimedGame(t, TimeControl{Base: time.Minute, Increment: 10 * time.Second, PerMove: 0})
+
+	if err := game.Attack(true, true); err != nil {
+		t.Fatalf("Game.Attack() error = %v", err)
+	}
+
+	now := time.Now()
+	// player 1 moved almost instantly and banked the increment
+	if got := game.RemainingTime(0, now); got <= time.Minute || got > time.Minute+10*time.Second {
+		t.Errorf("player 1 remaining = %v, want just under 1m10s", got)
+	}
+	if !game.Clocks[1].Running || game.Clocks[0].Running {
+		t.Errorf("clocks = %v, want only player 2 running", game.Clocks)
+	}
+	if got := game.RemainingTime(1, now.Add(15*time.Second)); got > 45*time.Second {
+		t.Errorf("player 2 remaining after 15s = %v, want at most 45s", got)
+	}
+}
+
+func TestGame_ClockPerMove(t *testing.T) {
+	game := newTimedGame(t, TimeControl{Base: 0, Increment: 0, PerMove: 5 * time.Second})
+
+	if err := game.Attack(true, true); err != nil {
+		t.Fatalf("Game.Attack() error = %v", err)
+	}
+	if got := game.RemainingTime(0, time.Now()); got != 5*time.Second {
+		t.Errorf("player 1 remaining = %v, want a fresh 5s", got)
+	}
+}
+
+func TestGame_CheckFlag(t *testing.T) {
+	game := newTimedGame(t, TimeControl{Base: time.Second, Increment: 0, PerMove: 0})
+
+	if game.CheckFlag(time.Now()) {
+		t.Fatalf("Game.CheckFlag() flagged a player with time left")
+	}
+	if !game.CheckFlag(time.Now().Add(2 * time.Second)) {
+		t.Fatalf("Game.CheckFlag() did not flag a player out of time")
+	}
+	if game.State != GameStateFinished || game.Result != ResultFlag || game.Winner != game.Players[1] {
+		t.Errorf("game state = %s result = %s winner = %v, want player 2 win on time", game.State, game.Result, game.Winner)
+	}
+	if err := game.Attack(true, true); err == nil {
+		t.Errorf("Game.Attack() after flag fall should fail")
+	}
+}
+
+func TestGame_MoveAfterFlagFall(t *testing.T) {
+	game := newTimedGame(t, TimeControl{Base: time.Millisecond, Increment: 0, PerMove: 0})
+	time.Sleep(5 * time.Millisecond)
+
+	// nobody called CheckFlag, so the late move is what ends the game
+	if err := game.Attack(true, true); !errors.Is(err, ErrFlagged) {
+		t.Fatalf("Game.Attack() error = %v, want %v", err, ErrFlagged)
+	}
+	if !game.IsOver() || game.Result != ResultFlag || game.Winner != game.Players[1] {
+		t.Errorf("game state = %s result = %s winner = %v, want player 2 win on time", game.State, game.Result, game.Winner)
+	}
+}
@@ -0,0 +1,93 @@
+// Command arena plays the built in bots against each other and reports how
+// each pairing went. In team games each bot plays both seats of its team.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/tkahng/sticks"
+	"github.com/tkahng/sticks/internal/cli"
+)
+
+func main() {
+	botList := flag.String("bots", "", "comma separated bot difficulties, every pair plays a match (default easy,medium,hard, plus perfect with two players)")
+	games := flag.Int("games", 1000, "games per match, alternating who moves first")
+	ruleset := cli.RulesetFlags(flag.CommandLine)
+	maxMoves := flag.Int("max-moves", 200, "draw games after this many moves, 0 for no limit")
+	z := flag.Float64("z", 1.96, "standard deviations for the confidence intervals")
+	flag.Parse()
+
+	rules, err := ruleset()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// The perfect bot solves the game, which it can only do for two players
+	if *botList == "" {
+		*botList = "easy,medium,hard"
+		if rules.Players == 2 {
+			*botList += ",perfect"
+		}
+	}
+
+	var bots []sticks.Bot
+	for _, difficulty := range strings.Split(*botList, ",") {
+		bot, err := sticks.NewBot(sticks.BotDifficulty(strings.TrimSpace(difficulty)), rules)
+		if err != nil {
+			log.Fatalf("Creating bot: %v", err)
+		}
+		bots = append(bots, bot)
+	}
+	if len(bots) < 2 {
+		log.Fatalf("Need at least two bots, got %d", len(bots))
+	}
+
+	arena := sticks.NewArena(rules)
+	arena.MaxMoves = *maxMoves
+
+	// Matches are independent, so play them all at once
+	var pairs [][2]sticks.Bot
+	for i := range bots {
+		for j := i + 1; j < len(bots); j++ {
+			pairs = append(pairs, [2]sticks.Bot{bots[i], bots[j]})
+		}
+	}
+	results := make([]sticks.MatchResult, len(pairs))
+	errs := make([]error, len(pairs))
+	var wg sync.WaitGroup
+	for i, pair := range pairs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = arena.PlayMatch(pair[0], pair[1], *games)
+		}()
+	}
+	wg.Wait()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "first\tsecond\tgames\twin\tdraw\tloss\tscore")
+	for i, r := range results {
+		if errs[i] != nil {
+			log.Fatalf("%s vs %s: %v", pairs[i][0].Name(), pairs[i][1].Name(), errs[i])
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\t%.3f\n",
+			r.Bots[0], r.Bots[1], r.Games,
+			rate(r.Wins, r.Games, *z), rate(r.Draws, r.Games, *z), rate(r.Losses, r.Games, *z),
+			r.Score())
+	}
+	if err := w.Flush(); err != nil {
+		log.Fatalf("Writing results: %v", err)
+	}
+}
+
+// rate formats a proportion with its confidence interval
+func rate(n, games int, z float64) string {
+	lo, hi := sticks.WilsonInterval(n, games, z)
+	return fmt.Sprintf("%5.1f%% [%4.1f-%4.1f]", 100*float64(n)/float64(max(games, 1)), 100*lo, 100*hi)
+}
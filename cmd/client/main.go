@@ -0,0 +1,369 @@
+// Command client plays sticks from a terminal over the server's WebSocket API.
+//
+// Moves are typed in game notation: Lx2R attacks player 2's right hand with
+// your left, LxR attacks the next opponent, L2 moves two points from your left
+// hand to your right and L>3R1 gives a teammate a point.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/tkahng/sticks"
+	"github.com/tkahng/sticks/internal/cli"
+	"github.com/tkahng/sticks/server"
+)
+
+const help = `commands:
+  <move>   a move in game notation: Lx2R, LxR, L2, L>3R1
+  hint     rank your moves (practice games only)
+  say ...  send a chat message
+  resign   give up the game
+  draw     offer a draw
+  accept   accept the draw on offer
+  decline  turn down the draw on offer
+  abort    call off the game before the first move
+  help     show this help
+  quit     leave the game`
+
+func main() {
+	addr := flag.String("addr", "ws://localhost:8080/api/ws", "server WebSocket URL")
+	playerID := flag.String("id", "", "player_id cookie from an earlier game, to keep your player ID and rating; the server issues one if empty")
+	bot := flag.String("bot", "", "play a bot of this difficulty instead of matchmaking")
+	mode := flag.String("mode", "", `matchmaking mode, "teams" for 2v2`)
+	players := flag.String("players", "", "number of seats in a free for all game, 2 to 6")
+	region := flag.String("region", "", "region to find opponents in, for servers that match by region")
+	puzzle := flag.String("puzzle", "", `play a puzzle by id, or "daily"`)
+	resume := flag.String("resume", "", "resume token of a game to rejoin")
+	flag.Parse()
+
+	u, err := url.Parse(*addr)
+	if err != nil {
+		log.Fatalf("Invalid address: %v", err)
+	}
+	query := u.Query()
+	for key, value := range map[string]string{"bot": *bot, "mode": *mode, "players": *players, "region": *region, "puzzle": *puzzle, "resume": *resume} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	header := http.Header{}
+	if *playerID != "" {
+		header.Set("Cookie", "player_id="+*playerID)
+	}
+	conn, resp, err := websocket.DefaultDialer.Dial(u.String(), header)
+	if err != nil {
+		log.Fatalf("Connecting to %s: %v", u, err)
+	}
+	// nolint:errcheck
+	defer conn.Close()
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == "player_id" && cookie.Value != *playerID {
+			fmt.Printf("pass -id %s to play as this player again\n", cookie.Value)
+		}
+	}
+
+	c := &client{
+		conn:     conn,
+		mutex:    &sync.Mutex{},
+		seat:     -1,
+		snapshot: nil,
+		done:     make(chan struct{}),
+	}
+	fmt.Println("connected, waiting for a game...")
+	go c.readForever()
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case line, ok := <-lines:
+			if !ok {
+				return
+			}
+			if err := c.command(strings.TrimSpace(line)); err != nil {
+				if errors.Is(err, errQuit) {
+					return
+				}
+				fmt.Println("error:", err)
+			}
+		}
+	}
+}
+
+var errQuit = errors.New("quit")
+
+// client tracks the game as the server reports it
+type client struct {
+	conn     *websocket.Conn
+	mutex    *sync.Mutex
+	seat     int // -1 until a game is matched
+	snapshot *sticks.GameSnapshot
+	done     chan struct{}
+}
+
+// readForever prints server messages until the connection closes
+func (c *client) readForever() {
+	defer close(c.done)
+	for {
+		var msg server.Message
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			fmt.Println("disconnected:", err)
+			return
+		}
+		if err := c.handle(msg); err != nil {
+			fmt.Printf("bad %s message: %v\n", msg.Type, err)
+		}
+	}
+}
+
+func (c *client) handle(msg server.Message) error {
+	switch msg.Type {
+	case server.MessageTypeQueueStatus:
+		var data struct {
+			Position        int   `json:"position"`
+			Waiting         int   `json:"waiting"`
+			EstimatedWaitMs int64 `json:"estimatedWaitMs"`
+		}
+		if err := json.Unmarshal(msg.Data, &data); err != nil {
+			return err
+		}
+		fmt.Printf("waiting for a match: %d of %d in the queue", data.Position, data.Waiting)
+		if data.EstimatedWaitMs > 0 {
+			fmt.Printf(", about %ds to go", (data.EstimatedWaitMs+999)/1000)
+		}
+		fmt.Println()
+
+	case server.MessageTypeGameMatched, server.MessageTypePuzzleStart:
+		var data struct {
+			Seat        int    `json:"seat"`
+			Moves       int    `json:"moves"`
+			ResumeToken string `json:"resumeToken"`
+		}
+		if err := json.Unmarshal(msg.Data, &data); err != nil {
+			return err
+		}
+		c.mutex.Lock()
+		c.seat = data.Seat
+		c.mutex.Unlock()
+		if msg.Type == server.MessageTypePuzzleStart {
+			fmt.Printf("puzzle: win in %d as player %d\n", data.Moves, data.Seat+1)
+		} else {
+			fmt.Printf("game found, you are player %d\n", data.Seat+1)
+			fmt.Printf("if you lose your connection, rejoin with -resume %s\n", data.ResumeToken)
+		}
+		fmt.Println(help)
+
+	case server.MessageTypeGameState:
+		var snapshot sticks.GameSnapshot
+		if err := json.Unmarshal(msg.Data, &snapshot); err != nil {
+			return err
+		}
+		c.mutex.Lock()
+		c.snapshot = &snapshot
+		seat := c.seat
+		c.mutex.Unlock()
+		fmt.Print(cli.Render(snapshot, seat))
+
+	case server.MessageTypeHint:
+		var data struct {
+			Moves []sticks.MoveAnalysis `json:"moves"`
+		}
+		if err := json.Unmarshal(msg.Data, &data); err != nil {
+			return err
+		}
+		for _, m := range data.Moves {
+			fmt.Printf("  %-6s %-7s %s\n", sticks.FormatMove(m.Move), m.Outcome, m.Reason)
+		}
+
+	case server.MessageTypeGameEnd, server.MessageTypePuzzleEnd:
+		var data struct {
+			Winner   string      `json:"winner"`
+			State    string      `json:"state"`
+			Result   string      `json:"result"`
+			Status   string      `json:"status"`
+			Solution sticks.Move `json:"solution"`
+		}
+		if err := json.Unmarshal(msg.Data, &data); err != nil {
+			return err
+		}
+		if msg.Type == server.MessageTypePuzzleEnd {
+			fmt.Printf("puzzle %s, the key move was %s\n", data.Status, sticks.FormatMove(data.Solution))
+			break
+		}
+		if data.Winner != "" {
+			fmt.Printf("game over: %s wins by %s\n", data.Winner, data.Result)
+		} else {
+			fmt.Printf("game over: %s by %s\n", data.State, data.Result)
+		}
+
+	case server.MessageTypeDrawOffer:
+		var data server.DrawOfferMessageData
+		if err := json.Unmarshal(msg.Data, &data); err != nil {
+			return err
+		}
+		fmt.Printf("%s offers a draw, type accept or decline\n", data.From)
+
+	case server.MessageTypeDrawDecline:
+		var data server.DrawDeclineMessageData
+		if err := json.Unmarshal(msg.Data, &data); err != nil {
+			return err
+		}
+		fmt.Printf("%s declined the draw\n", data.From)
+
+	case server.MessageTypeChat:
+		var data server.ChatMessageData
+		if err := json.Unmarshal(msg.Data, &data); err != nil {
+			return err
+		}
+		fmt.Printf("<%s> %s\n", data.From, data.Text)
+
+	case server.MessageTypePlayerDisconnected:
+		var data struct {
+			Seat    int   `json:"seat"`
+			GraceMs int64 `json:"graceMs"`
+		}
+		if err := json.Unmarshal(msg.Data, &data); err != nil {
+			return err
+		}
+		fmt.Printf("player %d disconnected, they have %ds to come back\n", data.Seat+1, data.GraceMs/1000)
+
+	case server.MessageTypePlayerReconnected:
+		var data struct {
+			Seat int `json:"seat"`
+		}
+		if err := json.Unmarshal(msg.Data, &data); err != nil {
+			return err
+		}
+		fmt.Printf("player %d is back\n", data.Seat+1)
+
+	case server.MessageTypeError:
+		var data server.ErrorMessageData
+		if err := json.Unmarshal(msg.Data, &data); err != nil {
+			return err
+		}
+		fmt.Printf("server: %s (%s)\n", data.Message, data.Code)
+
+	default:
+		fmt.Printf("%s: %s\n", msg.Type, msg.Data)
+	}
+	return nil
+}
+
+// command runs a line typed by the player
+func (c *client) command(line string) error {
+	switch strings.ToLower(line) {
+	case "":
+		return nil
+	case "help":
+		fmt.Println(help)
+		return nil
+	case "quit", "exit":
+		return errQuit
+	case "hint":
+		return c.send(server.MessageTypeHint, server.HintMessageData{})
+	case "resign":
+		return c.send(server.MessageTypeResign, server.ResignMessageData{})
+	case "draw":
+		return c.send(server.MessageTypeDrawOffer, server.DrawOfferMessageData{From: ""})
+	case "accept":
+		return c.send(server.MessageTypeDrawAccept, server.DrawAcceptMessageData{})
+	case "decline":
+		return c.send(server.MessageTypeDrawDecline, server.DrawDeclineMessageData{From: ""})
+	case "abort":
+		return c.send(server.MessageTypeAbort, server.AbortMessageData{})
+	}
+	if text, ok := strings.CutPrefix(line, "say "); ok {
+		return c.send(server.MessageTypeChat, server.ChatMessageData{From: "", Text: text})
+	}
+
+	move, err := c.parseMove(line)
+	if err != nil {
+		return err
+	}
+	switch move.Type {
+	case sticks.MoveTypeAttack:
+		target := move.Target
+		return c.send(server.MessageTypeAttack, server.AttackMessageData{
+			WithLeft:   move.WithLeft,
+			Target:     &target,
+			AttackLeft: move.TargetLeft,
+		})
+	case sticks.MoveTypeSplit:
+		return c.send(server.MessageTypeSplit, server.SplitMessageData{
+			WithLeft: move.WithLeft,
+			Points:   move.Points,
+		})
+	case sticks.MoveTypeTransfer:
+		return c.send(server.MessageTypeTransfer, server.TransferMessageData{
+			WithLeft: move.WithLeft,
+			Target:   move.Target,
+			ToLeft:   move.TargetLeft,
+			Points:   move.Points,
+		})
+	default:
+		return fmt.Errorf("unknown move %q", line)
+	}
+}
+
+// parseMove reads a notation token and checks it against the rules on the
+// last reported position, so mistakes are caught before they reach the server
+func (c *client) parseMove(token string) (sticks.Move, error) {
+	c.mutex.Lock()
+	snapshot, seat := c.snapshot, c.seat
+	c.mutex.Unlock()
+
+	if snapshot == nil {
+		return sticks.Move{}, fmt.Errorf("no game yet")
+	}
+	if snapshot.State != sticks.GameStateInProgress {
+		return sticks.Move{}, fmt.Errorf("game is %s", snapshot.State)
+	}
+	if snapshot.CurrentTurn != seat {
+		return sticks.Move{}, fmt.Errorf("not your turn")
+	}
+
+	game, err := snapshot.Position().Game(snapshot.Rules)
+	if err != nil {
+		return sticks.Move{}, err
+	}
+	move, err := game.ParseMove(token)
+	if err != nil {
+		return sticks.Move{}, err
+	}
+	for _, legal := range game.LegalMoves() {
+		if legal == move {
+			return move, nil
+		}
+	}
+	return sticks.Move{}, fmt.Errorf("%s is not a legal move here", token)
+}
+
+func (c *client) send(msgType server.MessageType, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return c.conn.WriteJSON(server.Message{Type: msgType, ID: "", Data: payload})
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
@@ -9,6 +10,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/tkahng/sticks"
 	"github.com/tkahng/sticks/server"
 	// Replace with your actual module path
 )
@@ -20,9 +22,32 @@ func main() {
 	const serverPort = ":8080"
 
 	// Create and start game server
-	srv := server.NewGameServer(maxConcurrentGames)
+	var options []server.Option
+	if value := os.Getenv("STICKS_RECONNECT_GRACE"); value != "" {
+		grace, err := time.ParseDuration(value)
+		if err != nil {
+			log.Fatalf("Invalid STICKS_RECONNECT_GRACE: %v", err)
+		}
+		options = append(options, server.WithReconnectGrace(grace))
+	}
+	if key := os.Getenv("STICKS_COOKIE_KEY"); key != "" {
+		options = append(options, server.WithCookieKey([]byte(key)))
+	}
+	matchmaker, err := matchmakerFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid matchmaking settings: %v", err)
+	}
+	options = append(options, server.WithBrokerOptions(sticks.WithMatchmaker(matchmaker)))
+	srv := server.NewGameServer(maxConcurrentGames, options...)
 	srv.Start()
 
+	// Serve a saved puzzle set instead of the generated one if configured
+	if path := os.Getenv("STICKS_PUZZLES"); path != "" {
+		if err := loadPuzzles(srv, path); err != nil {
+			log.Fatalf("Loading puzzles failed: %v", err)
+		}
+	}
+
 	// Create HTTP server
 	// nolint:exhaustruct
 	httpServer := &http.Server{
@@ -59,3 +84,45 @@ func main() {
 
 	log.Println("Server stopped")
 }
+
+// matchmakerFromEnv builds the matchmaker from the environment:
+//
+//	STICKS_MATCHMAKER          "rating" (the default) or "fifo"
+//	STICKS_MATCHMAKER_REGIONS  "true" to only match players in the same region
+//	STICKS_BOT_BACKFILL        how long before a bot takes a missing seat, none if unset
+func matchmakerFromEnv() (sticks.Matchmaker, error) {
+	newMatchmaker := func() sticks.Matchmaker {
+		return sticks.NewRatingMatchmaker(sticks.DefaultRatingWindow())
+	}
+	switch value := os.Getenv("STICKS_MATCHMAKER"); value {
+	case "", "rating":
+	case "fifo":
+		newMatchmaker = func() sticks.Matchmaker { return sticks.NewFIFOMatchmaker() }
+	default:
+		return nil, fmt.Errorf("unknown STICKS_MATCHMAKER %q", value)
+	}
+
+	matchmaker := newMatchmaker()
+	if os.Getenv("STICKS_MATCHMAKER_REGIONS") == "true" {
+		matchmaker = sticks.NewBucketedMatchmaker(sticks.ByRegion, newMatchmaker)
+	}
+	if value := os.Getenv("STICKS_BOT_BACKFILL"); value != "" {
+		after, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("STICKS_BOT_BACKFILL: %w", err)
+		}
+		matchmaker = sticks.NewBotBackfill(matchmaker, after, sticks.BotDifficultyMedium)
+	}
+	return matchmaker, nil
+}
+
+// loadPuzzles reads the puzzle set file at path into the server
+func loadPuzzles(srv *server.GameServer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	// nolint:errcheck
+	defer f.Close()
+	return srv.LoadPuzzles(f)
+}
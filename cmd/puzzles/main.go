@@ -0,0 +1,44 @@
+// Command puzzles generates a win in N puzzle set. The rules default to the
+// classic game and must be for two players, which is all the solver handles.
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/tkahng/sticks"
+	"github.com/tkahng/sticks/internal/cli"
+)
+
+func main() {
+	moves := flag.Int("moves", 3, "longest win to include, in the solver's moves")
+	out := flag.String("o", "puzzles.json", "file to write the puzzle set to")
+	ruleset := cli.RulesetFlags(flag.CommandLine)
+	flag.Parse()
+
+	rules, err := ruleset()
+	if err != nil {
+		log.Fatal(err)
+	}
+	tb, err := sticks.Solve(rules)
+	if err != nil {
+		log.Fatalf("Solving failed: %v", err)
+	}
+	set, err := sticks.GeneratePuzzles(tb, *moves)
+	if err != nil {
+		log.Fatalf("Generating puzzles failed: %v", err)
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		log.Fatalf("Creating %s failed: %v", *out, err)
+	}
+	if _, err := set.WriteTo(f); err != nil {
+		log.Fatalf("Writing %s failed: %v", *out, err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatalf("Writing %s failed: %v", *out, err)
+	}
+	log.Printf("Wrote %d puzzles to %s", len(set.Puzzles), *out)
+}
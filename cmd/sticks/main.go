@@ -0,0 +1,230 @@
+// Command sticks runs games locally, using the sticks package directly.
+//
+// Usage:
+//
+//	sticks play [flags]
+//
+// play runs a game on this terminal, either between humans taking turns at the
+// keyboard or against a bot. Moves are typed in game notation and the finished
+// game is written out in notation.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/tkahng/sticks"
+	"github.com/tkahng/sticks/internal/cli"
+)
+
+const usage = `usage: sticks <command> [flags]
+
+commands:
+  play    play a game on this terminal, hot seat or against a bot`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	switch os.Args[1] {
+	case "play":
+		if err := runPlay(os.Args[2:], os.Stdin, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+	case "help", "-h", "-help", "--help":
+		fmt.Println(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s\n", os.Args[1], usage)
+		os.Exit(2)
+	}
+}
+
+const playHelp = `commands:
+  <move>   a move in game notation: Lx2R, LxR, L2, L>3R1
+  moves    list the legal moves
+  undo     take back your last move
+  help     show this help
+  quit     stop the game and export it`
+
+// session is a game being played on one terminal, with a bot in any seat
+// that has one and a human in every other seat
+type session struct {
+	game *sticks.Game
+	bots []sticks.Bot
+	out  io.Writer
+}
+
+func runPlay(args []string, in io.Reader, out io.Writer) error {
+	flags := flag.NewFlagSet("play", flag.ExitOnError)
+	bot := flags.String("bot", "", "bot difficulty to play against: easy, medium, hard or perfect; empty for hot seat")
+	seat := flags.Int("seat", 1, "your seat when playing a bot, every other seat is a bot")
+	ruleset := cli.RulesetFlags(flags)
+	maxMoves := flags.Int("max-moves", 0, "draw the game after this many moves, 0 for no limit")
+	export := flags.String("o", "", "file to write the finished game to, standard output if empty")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	rules, err := ruleset()
+	if err != nil {
+		return err
+	}
+
+	s, err := newSession(rules, *bot, *seat-1, out)
+	if err != nil {
+		return err
+	}
+	s.game.MaxMoves = *maxMoves
+	if err := s.game.StartGame(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, playHelp)
+	if err := s.play(bufio.NewScanner(in)); err != nil {
+		return err
+	}
+	return s.export(*export)
+}
+
+// newSession seats the players: everyone is human in a hot seat game,
+// otherwise the human takes seat and bots take the rest
+func newSession(rules sticks.Ruleset, difficulty string, seat int, out io.Writer) (*session, error) {
+	s := &session{
+		game: sticks.NewGame("local", rules),
+		bots: make([]sticks.Bot, rules.Players),
+		out:  out,
+	}
+	// undo needs a practice game, and there is nobody to object on one terminal
+	s.game.Practice = true
+
+	if difficulty != "" {
+		if seat < 0 || seat >= rules.Players {
+			return nil, fmt.Errorf("seat must be between 1 and %d", rules.Players)
+		}
+		bot, err := sticks.NewBot(sticks.BotDifficulty(difficulty), rules)
+		if err != nil {
+			return nil, err
+		}
+		for i := range s.bots {
+			if i != seat {
+				s.bots[i] = bot
+			}
+		}
+	}
+
+	for i, bot := range s.bots {
+		name := fmt.Sprintf("Player %d", i+1)
+		if bot != nil {
+			name = bot.Name()
+		}
+		if err := s.game.AddPlayer(sticks.NewPlayer(fmt.Sprintf("seat%d", i+1), name)); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// play runs the game until it ends or a human quits
+func (s *session) play(scanner *bufio.Scanner) error {
+	for {
+		if err := s.playBots(); err != nil {
+			return err
+		}
+		fmt.Fprint(s.out, cli.Render(s.game.Snapshot(), -1))
+		if s.game.IsOver() {
+			return nil
+		}
+
+		fmt.Fprintf(s.out, "P%d> ", s.game.CurrentTurn+1)
+		if !scanner.Scan() {
+			fmt.Fprintln(s.out)
+			return scanner.Err()
+		}
+		line := strings.TrimSpace(scanner.Text())
+		switch strings.ToLower(line) {
+		case "":
+		case "help":
+			fmt.Fprintln(s.out, playHelp)
+		case "quit", "exit":
+			return nil
+		case "moves":
+			tokens := make([]string, 0)
+			for _, m := range s.game.LegalMoves() {
+				tokens = append(tokens, sticks.FormatMove(m))
+			}
+			fmt.Fprintln(s.out, strings.Join(tokens, " "))
+		case "undo":
+			if err := s.undo(); err != nil {
+				fmt.Fprintln(s.out, "error:", err)
+			}
+		default:
+			move, err := s.game.ParseMove(line)
+			if err == nil {
+				err = s.game.Apply(move)
+			}
+			if err != nil {
+				fmt.Fprintln(s.out, "error:", err)
+			}
+		}
+	}
+}
+
+// playBots plays moves for bots until a human is to move or the game ends
+func (s *session) playBots() error {
+	for !s.game.IsOver() {
+		bot := s.bots[s.game.CurrentTurn]
+		if bot == nil {
+			return nil
+		}
+		move, err := bot.ChooseMove(s.game)
+		if err != nil {
+			return fmt.Errorf("%s: %w", bot.Name(), err)
+		}
+		if err := s.game.Apply(move); err != nil {
+			return fmt.Errorf("%s played %v: %w", bot.Name(), move, err)
+		}
+		fmt.Fprintf(s.out, "%s plays %s\n", bot.Name(), sticks.FormatMove(move))
+	}
+	return nil
+}
+
+// undo takes back the last human move, along with any bot replies to it
+func (s *session) undo() error {
+	if err := s.game.Undo(); err != nil {
+		return err
+	}
+	for s.bots[s.game.CurrentTurn] != nil && len(s.game.MoveHistory()) > 0 {
+		if err := s.game.Undo(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// export writes the game in notation to path, or to the session's output
+func (s *session) export(path string) error {
+	if path == "" {
+		fmt.Fprintln(s.out)
+		return sticks.WriteNotation(s.out, s.game)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := sticks.WriteNotation(f, s.game); err != nil {
+		// nolint:errcheck
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(s.out, "game written to %s\n", path)
+	return nil
+}
@@ -0,0 +1,227 @@
+package sticks
+
+import (
+	"fmt"
+	"time"
+)
+
+// ResultReason records why a game ended
+type ResultReason string
+
+const (
+	ResultElimination ResultReason = "elimination" // a player lost both hands
+	ResultRepetition  ResultReason = "repetition"  // the same position came up three times
+	ResultMoveLimit   ResultReason = "move_limit"  // the game reached its maximum number of moves
+	ResultTimeout     ResultReason = "timeout"     // the game ran out of time without a result and was drawn
+	ResultFlag        ResultReason = "flag"        // a player's clock ran out
+	ResultAbandoned   ResultReason = "abandoned"   // a player left and did not come back
+	ResultResignation ResultReason = "resignation" // a player gave up
+	ResultAgreement   ResultReason = "agreement"   // every player still in the game agreed to a draw
+	ResultAborted     ResultReason = "aborted"     // the game was called off before the first move
+)
+
+// repetitionLimit is how many times a position may occur before the game is drawn
+const repetitionLimit = 3
+
+// IsOver reports whether the game has ended, either with a winner or a draw
+func (g *Game) IsOver() bool {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+	return g.over()
+}
+
+// over reports whether the game has ended without locking
+func (g *Game) over() bool {
+	return g.State == GameStateFinished || g.State == GameStateDraw || g.State == GameStateAborted
+}
+
+// Expire draws a game that ran out of time without a result
+func (g *Game) Expire() {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.over() {
+		return
+	}
+	g.draw(ResultTimeout)
+	g.stopClocks()
+}
+
+// Forfeit knocks a player out of a game in progress for reason, finishing the
+// game if only one team is left
+func (g *Game) Forfeit(playerID string, reason ResultReason) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.State != GameStateInProgress {
+		return fmt.Errorf("game is not in progress")
+	}
+	seat := g.seatOf(playerID)
+	if seat < 0 {
+		return fmt.Errorf("player %s is not in this game", playerID)
+	}
+	if !g.Players[seat].Alive() {
+		return fmt.Errorf("player %s is already out", playerID)
+	}
+
+	mover := g.CurrentTurn
+	g.eliminate(seat, reason)
+	if seat == mover {
+		g.pressClock(mover, time.Now())
+	}
+	if g.over() {
+		g.stopClocks()
+	}
+	return nil
+}
+
+// Resign knocks a player out of the game at their own request. In a game
+// between two teams the other team wins.
+func (g *Game) Resign(playerID string) error {
+	return g.Forfeit(playerID, ResultResignation)
+}
+
+// Abort calls off a game before its first move, without a result
+func (g *Game) Abort(playerID string) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.State != GameStateInProgress {
+		return fmt.Errorf("game is not in progress")
+	}
+	if g.seatOf(playerID) < 0 {
+		return fmt.Errorf("player %s is not in this game", playerID)
+	}
+	if len(g.History) > 0 {
+		return fmt.Errorf("a game can only be aborted before the first move")
+	}
+
+	g.State = GameStateAborted
+	g.Winner = nil
+	g.Result = ResultAborted
+	g.clearDrawOffer()
+	g.stopClocks()
+	return nil
+}
+
+// OfferDraw offers a draw to the other players. The game is drawn once every
+// player still in it accepts, and the offer lapses with the next move.
+func (g *Game) OfferDraw(playerID string) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if err := g.checkDrawVoter(playerID); err != nil {
+		return err
+	}
+	if g.DrawOfferedBy != "" {
+		return fmt.Errorf("a draw has already been offered")
+	}
+
+	g.DrawOfferedBy = playerID
+	g.drawVotes = map[string]bool{playerID: true}
+	return nil
+}
+
+// AcceptDraw agrees to the draw on offer, drawing the game if every player
+// still in it has now agreed
+func (g *Game) AcceptDraw(playerID string) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if err := g.checkDrawVoter(playerID); err != nil {
+		return err
+	}
+	if g.DrawOfferedBy == "" {
+		return fmt.Errorf("no draw has been offered")
+	}
+	if g.drawVotes[playerID] {
+		return fmt.Errorf("player %s has already agreed to the draw", playerID)
+	}
+
+	g.drawVotes[playerID] = true
+	for _, p := range g.Players {
+		if p.Alive() && !g.drawVotes[p.ID] {
+			return nil
+		}
+	}
+	g.clearDrawOffer()
+	g.draw(ResultAgreement)
+	g.stopClocks()
+	return nil
+}
+
+// DeclineDraw turns down the draw on offer
+func (g *Game) DeclineDraw(playerID string) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if err := g.checkDrawVoter(playerID); err != nil {
+		return err
+	}
+	if g.DrawOfferedBy == "" {
+		return fmt.Errorf("no draw has been offered")
+	}
+	if playerID == g.DrawOfferedBy {
+		return fmt.Errorf("cannot decline your own draw offer")
+	}
+
+	g.clearDrawOffer()
+	return nil
+}
+
+// checkDrawVoter checks that a player may take part in a draw offer without
+// locking
+func (g *Game) checkDrawVoter(playerID string) error {
+	if g.State != GameStateInProgress {
+		return fmt.Errorf("game is not in progress")
+	}
+	seat := g.seatOf(playerID)
+	if seat < 0 {
+		return fmt.Errorf("player %s is not in this game", playerID)
+	}
+	if !g.Players[seat].Alive() {
+		return fmt.Errorf("player %s is out of the game", playerID)
+	}
+	return nil
+}
+
+// clearDrawOffer withdraws any draw on offer without locking
+func (g *Game) clearDrawOffer() {
+	g.DrawOfferedBy = ""
+	g.drawVotes = nil
+}
+
+// stopClocks stops every clock once the game is over without locking
+func (g *Game) stopClocks() {
+	for i := range g.Clocks {
+		g.Clocks[i].Running = false
+	}
+}
+
+// checkDraw counts the position just reached and draws the game on threefold
+// repetition or once the move limit is hit
+func (g *Game) checkDraw() {
+	if g.State != GameStateInProgress {
+		return
+	}
+
+	if g.repetitions == nil {
+		g.repetitions = map[Position]int{}
+	}
+	p := g.position().Canonical()
+	g.repetitions[p]++
+
+	switch {
+	case g.repetitions[p] >= repetitionLimit:
+		g.draw(ResultRepetition)
+	case g.MaxMoves > 0 && len(g.History) >= g.MaxMoves:
+		g.draw(ResultMoveLimit)
+	}
+}
+
+// draw ends the game without a winner
+func (g *Game) draw(reason ResultReason) {
+	g.State = GameStateDraw
+	g.Winner = nil
+	g.Result = reason
+}
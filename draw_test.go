@@ -0,0 +1,240 @@
+package sticks
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGame_ThreefoldRepetition(t *testing.T) {
+	game := newStartedGame(t, DefaultRuleset())
+
+	// both players shuffle a point back and forth, returning to the start
+	// position every four moves
+	shuffle := []Move{SplitMove(true, 1), SplitMove(true, 1), SplitMove(false, 1), SplitMove(false, 1)}
+	for i := range 8 {
+		if game.IsOver() {
+			t.Fatalf("game ended early after %d moves", i)
+		}
+		if err := game.Apply(shuffle[i%len(shuffle)]); err != nil {
+			t.Fatalf("Game.Apply() error = %v", err)
+		}
+	}
+
+	if game.State != GameStateDraw || game.Result != ResultRepetition {
+		t.Fatalf("game state = %s result = %s, want draw by repetition", game.State, game.Result)
+	}
+	if game.Winner != nil {
+		t.Errorf("drawn game has winner %v", game.Winner)
+	}
+	if err := game.Apply(AttackMove(1, true, true)); err == nil {
+		t.Errorf("Game.Apply() after a draw should fail")
+	}
+
+	var buf bytes.Buffer
+	if err := WriteNotation(&buf, game); err != nil {
+		t.Fatalf("WriteNotation() error = %v", err)
+	}
+	decoded, err := ReadNotation(&buf)
+	if err != nil {
+		t.Fatalf("ReadNotation() error = %v", err)
+	}
+	if decoded.State != GameStateDraw || decoded.Result != ResultRepetition {
+		t.Errorf("ReadNotation() state = %s result = %s, want draw by repetition", decoded.State, decoded.Result)
+	}
+}
+
+func TestGame_MoveLimit(t *testing.T) {
+	game := newStartedGame(t, DefaultRuleset())
+	game.MaxMoves = 2
+
+	if err := game.Attack(true, true); err != nil {
+		t.Fatalf("Game.Attack() error = %v", err)
+	}
+	if game.IsOver() {
+		t.Fatalf("game ended before the move limit")
+	}
+	if err := game.Attack(true, true); err != nil {
+		t.Fatalf("Game.Attack() error = %v", err)
+	}
+	if game.State != GameStateDraw || game.Result != ResultMoveLimit {
+		t.Errorf("game state = %s result = %s, want draw by move limit", game.State, game.Result)
+	}
+}
+
+func TestGame_UndoDraw(t *testing.T) {
+	game := newStartedGame(t, DefaultRuleset())
+	game.Practice = true
+	game.MaxMoves = 1
+
+	if err := game.Attack(true, true); err != nil {
+		t.Fatalf("Game.Attack() error = %v", err)
+	}
+	// a finished game stays finished
+	if err := game.Undo(); err == nil {
+		t.Errorf("Game.Undo() after the game was drawn should fail")
+	}
+	if game.State != GameStateDraw || game.Result != ResultMoveLimit {
+		t.Errorf("after undo state = %s result = %s, want draw by move limit", game.State, game.Result)
+	}
+}
+
+func TestGame_Expire(t *testing.T) {
+	game := newTimedGame(t, TimeControl{Base: time.Minute, Increment: 0, PerMove: 0})
+	game.Expire()
+	if game.State != GameStateDraw || game.Result != ResultTimeout {
+		t.Errorf("game state = %s result = %s, want draw by timeout", game.State, game.Result)
+	}
+	if game.Clocks[0].Running {
+		t.Errorf("first player's clock still running after the game expired")
+	}
+
+	var buf bytes.Buffer
+	if err := WriteNotation(&buf, game); err != nil {
+		t.Fatalf("WriteNotation() error = %v", err)
+	}
+	for _, tag := range []string{`[Result "1/2-1/2"]`, `[Termination "timeout"]`} {
+		if !strings.Contains(buf.String(), tag) {
+			t.Errorf("WriteNotation() = %q, want %s", buf.String(), tag)
+		}
+	}
+}
+
+func TestGame_Forfeit(t *testing.T) {
+	game := newStartedGame(t, DefaultRuleset())
+	if err := game.Forfeit("player 1", ResultAbandoned); err != nil {
+		t.Fatalf("Game.Forfeit() error = %v", err)
+	}
+	if game.State != GameStateFinished || game.Result != ResultAbandoned || game.Winner != game.Players[1] {
+		t.Errorf("game state = %s result = %s winner = %v, want player 2 winning by abandonment", game.State, game.Result, game.Winner)
+	}
+	if err := game.Forfeit("player 2", ResultAbandoned); err == nil {
+		t.Errorf("Game.Forfeit() after the game ended should fail")
+	}
+
+	rules := DefaultRuleset()
+	rules.Players = 3
+	game = newStartedGame(t, rules)
+	if err := game.Forfeit("nobody", ResultAbandoned); err == nil {
+		t.Errorf("Game.Forfeit() for a player not in the game should fail")
+	}
+	if err := game.Forfeit(game.Players[0].ID, ResultAbandoned); err != nil {
+		t.Fatalf("Game.Forfeit() error = %v", err)
+	}
+	if game.State != GameStateInProgress || game.CurrentTurn != 1 {
+		t.Errorf("game state = %s turn = %d, want play to pass to seat 1", game.State, game.CurrentTurn)
+	}
+	if err := game.Forfeit(game.Players[0].ID, ResultAbandoned); err == nil {
+		t.Errorf("Game.Forfeit() twice should fail")
+	}
+}
+
+func TestGame_Resign(t *testing.T) {
+	game := newStartedGame(t, TeamRuleset())
+	if err := game.Resign("player 2"); err != nil {
+		t.Fatalf("Game.Resign() error = %v", err)
+	}
+	if game.State != GameStateInProgress {
+		t.Fatalf("game state = %s, want play to go on with player 2's teammate", game.State)
+	}
+	if err := game.Resign("player 4"); err != nil {
+		t.Fatalf("Game.Resign() error = %v", err)
+	}
+	if game.State != GameStateFinished || game.Result != ResultResignation || game.Winner.Team != 0 {
+		t.Errorf("game state = %s result = %s winner = %v, want team 1 winning by resignation", game.State, game.Result, game.Winner)
+	}
+}
+
+func TestGame_Abort(t *testing.T) {
+	game := newStartedGame(t, DefaultRuleset())
+	game.TimeControl = &TimeControl{Base: time.Minute, Increment: 0, PerMove: 0}
+	game.startClocks(time.Now())
+	if err := game.Abort("nobody"); err == nil {
+		t.Errorf("Game.Abort() by a player not in the game should fail")
+	}
+	if err := game.Abort("player 2"); err != nil {
+		t.Fatalf("Game.Abort() error = %v", err)
+	}
+	if game.State != GameStateAborted || game.Result != ResultAborted || game.Winner != nil || !game.IsOver() {
+		t.Errorf("game state = %s result = %s winner = %v, want aborted", game.State, game.Result, game.Winner)
+	}
+	if game.Clocks[0].Running {
+		t.Errorf("clock still running after the game was aborted")
+	}
+
+	game = newStartedGame(t, DefaultRuleset())
+	if err := game.Apply(AttackMove(1, true, true)); err != nil {
+		t.Fatalf("Game.Apply() error = %v", err)
+	}
+	if err := game.Abort("player 2"); err == nil {
+		t.Errorf("Game.Abort() after the first move should fail")
+	}
+}
+
+func TestGame_DrawOffer(t *testing.T) {
+	game := newStartedGame(t, DefaultRuleset())
+	if err := game.AcceptDraw("player 2"); err == nil {
+		t.Errorf("Game.AcceptDraw() with no offer should fail")
+	}
+	if err := game.OfferDraw("player 1"); err != nil {
+		t.Fatalf("Game.OfferDraw() error = %v", err)
+	}
+	if err := game.OfferDraw("player 2"); err == nil {
+		t.Errorf("Game.OfferDraw() with an offer pending should fail")
+	}
+	if err := game.DeclineDraw("player 1"); err == nil {
+		t.Errorf("Game.DeclineDraw() of your own offer should fail")
+	}
+	if err := game.DeclineDraw("player 2"); err != nil {
+		t.Fatalf("Game.DeclineDraw() error = %v", err)
+	}
+	if game.DrawOfferedBy != "" {
+		t.Errorf("draw offer by %s still open after it was declined", game.DrawOfferedBy)
+	}
+
+	// an offer lapses with the next move
+	if err := game.OfferDraw("player 1"); err != nil {
+		t.Fatalf("Game.OfferDraw() error = %v", err)
+	}
+	if err := game.Apply(AttackMove(1, true, true)); err != nil {
+		t.Fatalf("Game.Apply() error = %v", err)
+	}
+	if err := game.AcceptDraw("player 2"); err == nil {
+		t.Errorf("Game.AcceptDraw() after the offer lapsed should fail")
+	}
+
+	if err := game.OfferDraw("player 2"); err != nil {
+		t.Fatalf("Game.OfferDraw() error = %v", err)
+	}
+	if err := game.AcceptDraw("player 2"); err == nil {
+		t.Errorf("Game.AcceptDraw() of your own offer should fail")
+	}
+	if err := game.AcceptDraw("player 1"); err != nil {
+		t.Fatalf("Game.AcceptDraw() error = %v", err)
+	}
+	if game.State != GameStateDraw || game.Result != ResultAgreement {
+		t.Errorf("game state = %s result = %s, want a draw by agreement", game.State, game.Result)
+	}
+}
+
+func TestGame_DrawOfferNeedsEveryone(t *testing.T) {
+	rules := DefaultRuleset()
+	rules.Players = 3
+	game := newStartedGame(t, rules)
+	if err := game.OfferDraw("player 1"); err != nil {
+		t.Fatalf("Game.OfferDraw() error = %v", err)
+	}
+	if err := game.AcceptDraw("player 2"); err != nil {
+		t.Fatalf("Game.AcceptDraw() error = %v", err)
+	}
+	if game.IsOver() {
+		t.Fatalf("game drawn before player 3 agreed")
+	}
+	if err := game.AcceptDraw("player 3"); err != nil {
+		t.Fatalf("Game.AcceptDraw() error = %v", err)
+	}
+	if game.State != GameStateDraw {
+		t.Errorf("game state = %s, want draw", game.State)
+	}
+}
@@ -2,6 +2,8 @@ package sticks
 
 import (
 	"fmt"
+	"maps"
+	"slices"
 	"sync"
 	"time"
 )
@@ -14,28 +16,51 @@ const (
 	GameStateReady      GameState = "ready"
 	GameStateInProgress GameState = "in_progress"
 	GameStateFinished   GameState = "finished"
+	GameStateDraw       GameState = "draw"
+	GameStateAborted    GameState = "aborted" // called off before the first move, with no result
 )
 
 type GameInterface interface {
 	AddPlayer(player *Player) error
 	Attack(attackWithLeft bool, attackLeft bool) error
+	AttackPlayer(target int, attackWithLeft bool, attackLeft bool) error
 	GetCurrentPlayer() *Player
 	GetOpponent() *Player
+	GetOpponents() []*Player
 	Split(fromLeft bool, points int) error
+	Transfer(target int, fromLeft bool, toLeft bool, points int) error
 	StartGame() error
+	Apply(m Move) error
+	LegalMoves() []Move
+	Undo() error
+	Redo() error
 	EndTurn()
 	PrintScore()
 }
 
 type Game struct {
 	ID          string
-	Player1     *Player
-	Player2     *Player
-	CurrentTurn int       `json:"currentTurn"` // 0 for player1, 1 for player2
-	State       GameState `json:"state"`
-	Winner      *Player   `json:"winner,omitempty"`
-	CreatedAt   time.Time `json:"createdAt"`
-	mutex       *sync.RWMutex
+	Players     []*Player    `json:"players"`     // in turn order
+	CurrentTurn int          `json:"currentTurn"` // index into Players
+	State       GameState    `json:"state"`
+	Winner      *Player      `json:"winner,omitempty"` // in team games their teammate shares the win
+	Result      ResultReason `json:"result,omitempty"` // why the game ended
+	CreatedAt   time.Time    `json:"createdAt"`
+	Rules       Ruleset      `json:"rules"`
+	History     []MoveRecord `json:"history"`
+	Practice    bool         `json:"practice"`              // practice games allow undo and redo
+	MaxMoves    int          `json:"maxMoves,omitempty"`    // draw after this many moves, 0 for no limit
+	TimeControl *TimeControl `json:"timeControl,omitempty"` // nil for untimed games
+	Clocks      []Clock      `json:"clocks,omitempty"`      // indexed by turn
+	// DrawOfferedBy is the ID of the player offering a draw, empty for none.
+	// An offer lapses when the next move is played.
+	DrawOfferedBy string          `json:"drawOfferedBy,omitempty"`
+	drawVotes     map[string]bool // players who agreed to the offered draw
+	redo          []MoveRecord
+	teamMovers    [2]int // by team in team games, 1 + the seat that last moved for it, 0 for nobody yet
+	turnStarted   time.Time
+	repetitions   map[Position]int // keyed by canonical position
+	mutex         *sync.RWMutex
 }
 
 // PrintScore implements GameInterface.
@@ -43,8 +68,9 @@ func (g *Game) PrintScore() {
 	g.mutex.RLock()
 	defer g.mutex.RUnlock()
 
-	fmt.Printf("Player 1: %d, %d\n", g.Player1.LeftHand.fingers, g.Player1.RightHand.fingers)
-	fmt.Printf("Player 2: %d, %d\n", g.Player2.LeftHand.fingers, g.Player2.RightHand.fingers)
+	for i, p := range g.Players {
+		fmt.Printf("Player %d: %d, %d\n", i+1, p.LeftHand.fingers, p.RightHand.fingers)
+	}
 }
 
 // GetTurn implements GameInterface.
@@ -56,64 +82,236 @@ func (g *Game) GetTurn() int {
 
 var _ GameInterface = (*Game)(nil)
 
-func NewGame(id string) *Game {
+// NewGame creates a game played under the given rules
+func NewGame(id string, rules Ruleset) *Game {
 	return &Game{
-		ID:          id,
-		State:       GameStateWaiting,
-		CreatedAt:   time.Now(),
-		Player1:     nil,
-		Player2:     nil,
-		CurrentTurn: 0,
-		Winner:      nil,
-		mutex:       &sync.RWMutex{},
+		ID:            id,
+		State:         GameStateWaiting,
+		CreatedAt:     time.Now(),
+		Players:       nil,
+		CurrentTurn:   0,
+		Winner:        nil,
+		Result:        "",
+		Rules:         rules,
+		History:       nil,
+		Practice:      false,
+		MaxMoves:      0,
+		TimeControl:   nil,
+		Clocks:        nil,
+		DrawOfferedBy: "",
+		drawVotes:     nil,
+		redo:          nil,
+		teamMovers:    [2]int{},
+		turnStarted:   time.Time{},
+		repetitions:   nil,
+		mutex:         &sync.RWMutex{},
 	}
 }
 
+// EndTurn passes the turn to the next player still in the game
 func (g *Game) EndTurn() {
-	g.CurrentTurn = 1 - g.CurrentTurn
+	if g.Rules.Teams {
+		g.teamMovers[g.currentPlayer().Team] = g.CurrentTurn + 1
+	}
+	g.CurrentTurn = g.nextTurn(g.CurrentTurn)
 }
 
 // Attack implements GameInterface.
-// Attack performs an attack move
+// Attack performs an attack move against the next opponent in turn order
 func (g *Game) Attack(attackerIsLeft bool, defenderIsLeft bool) error {
-	fmt.Printf("attacking\n")
 	g.mutex.Lock()
 	defer g.mutex.Unlock()
 
-	fmt.Printf("locked\n")
+	if g.State != GameStateInProgress {
+		return fmt.Errorf("game is not in progress")
+	}
+
+	// the target is picked under the same lock as the move, so it is still
+	// the next opponent when the attack lands
+	return g.record(AttackMove(g.nextOpponent(), attackerIsLeft, defenderIsLeft))
+}
+
+// AttackPlayer performs an attack move against the player at seat target
+func (g *Game) AttackPlayer(target int, attackerIsLeft bool, defenderIsLeft bool) error {
+	return g.Apply(AttackMove(target, attackerIsLeft, defenderIsLeft))
+}
+
+// Split performs a split move
+func (g *Game) Split(fromLeft bool, newLeftPoints int) error {
+	return g.Apply(SplitMove(fromLeft, newLeftPoints))
+}
+
+// Transfer gives points to the toLeft hand of the teammate at seat target
+func (g *Game) Transfer(target int, fromLeft bool, toLeft bool, points int) error {
+	return g.Apply(TransferMove(target, fromLeft, toLeft, points))
+}
+
+// Apply plays a move for the current player
+func (g *Game) Apply(m Move) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
 
 	if g.State != GameStateInProgress {
-		fmt.Printf("game is not in progress\n")
 		return fmt.Errorf("game is not in progress")
 	}
 
-	fmt.Printf("getting players\n")
+	return g.record(m)
+}
 
-	// Get players directly without calling methods that acquire locks
-	var attacker, defender *Player
-	if g.CurrentTurn == 0 {
-		attacker = g.Player1
-		defender = g.Player2
-	} else {
-		attacker = g.Player2
-		defender = g.Player1
+// LegalMoves returns every move the current player may make
+func (g *Game) LegalMoves() []Move {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+
+	if g.State != GameStateInProgress {
+		return nil
+	}
+
+	return g.legalMoves()
+}
+
+// currentPlayer returns the player to move without locking
+func (g *Game) currentPlayer() *Player {
+	return g.Players[g.CurrentTurn]
+}
+
+// nextTurn returns the seat of the next living player after turn, or turn
+// itself if nobody else is left
+func (g *Game) nextTurn(turn int) int {
+	if g.Rules.Teams {
+		return g.nextTeamTurn(turn)
+	}
+	for i := 1; i < len(g.Players); i++ {
+		next := (turn + i) % len(g.Players)
+		if g.Players[next].Alive() {
+			return next
+		}
+	}
+	return turn
+}
+
+// nextTeamTurn returns the seat of the next living player on the other team
+// after the last of them to move, so the teams alternate however many players
+// each has left and teammates take turns in seat order
+func (g *Game) nextTeamTurn(turn int) int {
+	team := g.Players[turn].Team
+	from := turn
+	if last := g.teamMovers[1-team]; last > 0 {
+		from = last - 1
+	}
+	// the other team's last mover may be the only one of them left
+	for i := 1; i <= len(g.Players); i++ {
+		next := (from + i) % len(g.Players)
+		if p := g.Players[next]; p.Alive() && p.Team != team {
+			return next
+		}
 	}
+	return turn
+}
 
-	fmt.Printf("got players\n")
+// opponents returns the living players on other teams than the player to move
+// without locking
+func (g *Game) opponents() []*Player {
+	var opponents []*Player
+	for i := 1; i < len(g.Players); i++ {
+		p := g.Players[(g.CurrentTurn+i)%len(g.Players)]
+		if p.Alive() && p.Team != g.currentPlayer().Team {
+			opponents = append(opponents, p)
+		}
+	}
+	return opponents
+}
 
-	attackerHand := attacker.GetHand(attackerIsLeft)
-	defenderHand := defender.GetHand(defenderIsLeft)
+// nextOpponent returns the seat of the first living opponent after the player
+// to move, or the player to move if nobody is left, without locking
+func (g *Game) nextOpponent() int {
+	for i := 1; i < len(g.Players); i++ {
+		next := (g.CurrentTurn + i) % len(g.Players)
+		if p := g.Players[next]; p.Alive() && p.Team != g.currentPlayer().Team {
+			return next
+		}
+	}
+	return g.CurrentTurn
+}
+
+// check validates a move for the current player without playing it
+func (g *Game) check(m Move) error {
+	player := g.currentPlayer()
+
+	switch m.Type {
+	case MoveTypeAttack:
+		if m.Target < 0 || m.Target >= len(g.Players) {
+			return fmt.Errorf("no player at seat %d", m.Target)
+		}
+		if m.Target == g.CurrentTurn {
+			return fmt.Errorf("cannot attack yourself")
+		}
+		if g.Players[m.Target].Team == player.Team {
+			return fmt.Errorf("cannot attack a teammate")
+		}
+		_, err := g.Rules.attack(player.GetHand(m.WithLeft).fingers, g.Players[m.Target].GetHand(m.TargetLeft).fingers)
+		return err
+	case MoveTypeSplit:
+		_, _, err := g.Rules.split(player.GetHand(m.WithLeft).fingers, player.GetHand(!m.WithLeft).fingers, m.Points)
+		return err
+	case MoveTypeTransfer:
+		if !g.Rules.TeamTransfers {
+			return fmt.Errorf("transfers are not allowed in this game")
+		}
+		if m.Target < 0 || m.Target >= len(g.Players) {
+			return fmt.Errorf("no player at seat %d", m.Target)
+		}
+		teammate := g.Players[m.Target]
+		if m.Target == g.CurrentTurn || teammate.Team != player.Team {
+			return fmt.Errorf("can only transfer to a teammate")
+		}
+		if !teammate.Alive() {
+			return fmt.Errorf("teammate is out of the game")
+		}
+		from, _, err := g.Rules.transfer(player.GetHand(m.WithLeft).fingers, teammate.GetHand(m.TargetLeft).fingers, m.Points)
+		if err != nil {
+			return err
+		}
+		if !g.Rules.alive(from) && !player.GetHand(!m.WithLeft).Alive() {
+			return fmt.Errorf("cannot give away your last fingers")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown move type: %s", m.Type)
+	}
+}
 
-	// Perform attack
-	err := attackerHand.Attack(defenderHand)
-	if err != nil {
+// apply plays a move for the current player without locking
+func (g *Game) apply(m Move) error {
+	if err := g.check(m); err != nil {
 		return err
 	}
 
+	player := g.currentPlayer()
+
+	switch m.Type {
+	case MoveTypeAttack:
+		if err := player.GetHand(m.WithLeft).Attack(g.Players[m.Target].GetHand(m.TargetLeft)); err != nil {
+			return err
+		}
+	case MoveTypeSplit:
+		from := player.GetHand(m.WithLeft)
+		other := player.GetHand(!m.WithLeft)
+		if err := other.Take(from, m.Points); err != nil {
+			return err
+		}
+	case MoveTypeTransfer:
+		to := g.Players[m.Target].GetHand(m.TargetLeft)
+		if err := to.Take(player.GetHand(m.WithLeft), m.Points); err != nil {
+			return err
+		}
+	}
+
 	// Check if game is over
-	if !defender.Alive() {
+	if len(g.opponents()) == 0 {
 		g.State = GameStateFinished
-		g.Winner = attacker
+		g.Winner = player
+		g.Result = ResultElimination
 	} else {
 		// Switch turns
 		g.EndTurn()
@@ -122,34 +320,83 @@ func (g *Game) Attack(attackerIsLeft bool, defenderIsLeft bool) error {
 	return nil
 }
 
-// Split performs a split move
-func (g *Game) Split(fromLeft bool, newLeftPoints int) error {
-	g.mutex.Lock()
-	defer g.mutex.Unlock()
-
-	if g.State != GameStateInProgress {
-		return fmt.Errorf("game is not in progress")
+// legalMoves enumerates the current player's moves without locking
+func (g *Game) legalMoves() []Move {
+	player := g.currentPlayer()
+
+	var candidates []Move
+	for target := range g.Players {
+		for _, withLeft := range []bool{true, false} {
+			for _, targetLeft := range []bool{true, false} {
+				candidates = append(candidates, AttackMove(target, withLeft, targetLeft))
+			}
+		}
 	}
-
-	// Get current player directly without calling methods that acquire locks
-	var player *Player
-	if g.CurrentTurn == 0 {
-		player = g.Player1
-	} else {
-		player = g.Player2
+	for _, fromLeft := range []bool{true, false} {
+		for points := 1; points <= player.GetHand(fromLeft).fingers; points++ {
+			candidates = append(candidates, SplitMove(fromLeft, points))
+			if !g.Rules.TeamTransfers {
+				continue
+			}
+			for target := range g.Players {
+				for _, toLeft := range []bool{true, false} {
+					candidates = append(candidates, TransferMove(target, fromLeft, toLeft, points))
+				}
+			}
+		}
 	}
 
-	from := player.GetHand(fromLeft)
-	other := player.GetHand(!fromLeft)
-	err := other.Take(from, newLeftPoints)
-	if err != nil {
-		return err
+	moves := make([]Move, 0, len(candidates))
+	for _, m := range candidates {
+		if g.check(m) == nil {
+			moves = append(moves, m)
+		}
 	}
+	return moves
+}
 
-	// Switch turns
-	g.EndTurn()
+// eliminate knocks the player at seat out of the game without locking,
+// finishing the game if only one team is left
+func (g *Game) eliminate(seat int, reason ResultReason) {
+	player := g.Players[seat]
+	player.LeftHand.fingers = 0
+	player.RightHand.fingers = 0
+
+	var alive []*Player
+	teams := map[int]bool{}
+	for _, p := range g.Players {
+		if p.Alive() {
+			alive = append(alive, p)
+			teams[p.Team] = true
+		}
+	}
+	switch {
+	case len(teams) == 1:
+		g.State = GameStateFinished
+		g.Winner = alive[0]
+		g.Result = reason
+	case seat == g.CurrentTurn:
+		g.EndTurn()
+	}
+}
 
-	return nil
+// clone returns a deep copy of the game without locking
+func (g *Game) clone() *Game {
+	c := *g
+	c.Players = make([]*Player, len(g.Players))
+	for i, p := range g.Players {
+		c.Players[i] = p.clone()
+		if p == g.Winner {
+			c.Winner = c.Players[i]
+		}
+	}
+	c.History = append([]MoveRecord(nil), g.History...)
+	c.redo = append([]MoveRecord(nil), g.redo...)
+	c.repetitions = maps.Clone(g.repetitions)
+	c.Clocks = slices.Clone(g.Clocks)
+	c.drawVotes = maps.Clone(g.drawVotes)
+	c.mutex = &sync.RWMutex{}
+	return &c
 }
 
 // StartGame implements GameInterface.
@@ -161,12 +408,25 @@ func (g *Game) StartGame() error {
 		return fmt.Errorf("game is not ready to start")
 	}
 
-	if g.Player1 == nil || g.Player2 == nil {
-		return fmt.Errorf("need two players to start")
+	if err := g.Rules.Validate(); err != nil {
+		return err
+	}
+
+	if len(g.Players) != g.Rules.Players {
+		return fmt.Errorf("need %d players to start", g.Rules.Players)
+	}
+
+	if g.TimeControl != nil {
+		if err := g.TimeControl.Validate(); err != nil {
+			return err
+		}
 	}
 
 	g.State = GameStateInProgress
-	g.CurrentTurn = 0 // Player1 starts
+	g.CurrentTurn = 0 // first seat starts
+	g.teamMovers = [2]int{}
+	g.repetitions = map[Position]int{g.position().Canonical(): 1}
+	g.startClocks(time.Now())
 	return nil
 }
 
@@ -174,21 +434,47 @@ func (g *Game) GetCurrentPlayer() *Player {
 	g.mutex.RLock()
 	defer g.mutex.RUnlock()
 
-	if g.CurrentTurn == 0 {
-		return g.Player1
+	if g.CurrentTurn >= len(g.Players) {
+		return nil
 	}
-	return g.Player2
+	return g.Players[g.CurrentTurn]
 }
 
-// GetOpponent returns the opponent of the current player
+// GetOpponent returns the next living opponent of the current player in turn
+// order; in a two player game, the only opponent
 func (g *Game) GetOpponent() *Player {
 	g.mutex.RLock()
 	defer g.mutex.RUnlock()
 
-	if g.CurrentTurn == 0 {
-		return g.Player2
+	if opponents := g.opponents(); len(opponents) > 0 {
+		return opponents[0]
+	}
+	return nil
+}
+
+// GetOpponents returns every living opponent of the current player in turn
+// order
+func (g *Game) GetOpponents() []*Player {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+	return g.opponents()
+}
+
+// PlayerIndex returns the seat of the player with id, or -1
+func (g *Game) PlayerIndex(id string) int {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+	return g.seatOf(id)
+}
+
+// seatOf returns the seat of the player with id without locking
+func (g *Game) seatOf(id string) int {
+	for i, p := range g.Players {
+		if p.ID == id {
+			return i
+		}
 	}
-	return g.Player1
+	return -1
 }
 
 func (g *Game) AddPlayer(player *Player) error {
@@ -199,16 +485,15 @@ func (g *Game) AddPlayer(player *Player) error {
 		return fmt.Errorf("game is not accepting players")
 	}
 
-	if g.Player1 == nil {
-		g.Player1 = player
-		return nil
+	if len(g.Players) >= g.Rules.Players {
+		return fmt.Errorf("game is full")
 	}
 
-	if g.Player2 == nil {
-		g.Player2 = player
+	player.applyRuleset(g.Rules)
+	player.Team = g.Rules.team(len(g.Players))
+	g.Players = append(g.Players, player)
+	if len(g.Players) == g.Rules.Players {
 		g.State = GameStateReady
-		return nil
 	}
-
-	return fmt.Errorf("game is full")
+	return nil
 }
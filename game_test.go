@@ -2,6 +2,8 @@ package sticks
 
 import (
 	"errors"
+	"fmt"
+	"slices"
 	"testing"
 )
 
@@ -59,7 +61,7 @@ func TestGame_AddPlayer(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			g := NewGame(tt.fields.ID)
+			g := NewGame(tt.fields.ID, DefaultRuleset())
 			if tt.fields.Player1 != nil {
 				err := g.AddPlayer(tt.fields.Player1)
 				if err != nil {
@@ -82,7 +84,7 @@ func TestGame_AddPlayer(t *testing.T) {
 func TestGame_Attack(t *testing.T) {
 	player1 := NewPlayer("player 1", "")
 	player2 := NewPlayer("player 2", "")
-	game := NewGame("game")
+	game := NewGame("game", DefaultRuleset())
 
 	if err := errors.Join(game.AddPlayer(player1), game.AddPlayer(player2)); err != nil {
 		t.Errorf("Game.AddPlayer() error = %v", err)
@@ -116,3 +118,253 @@ func TestGame_Attack(t *testing.T) {
 	// game.
 	game.PrintScore()
 }
+
+func TestGame_Rollover(t *testing.T) {
+	rules := DefaultRuleset()
+	rules.Variant = RuleVariantRollover
+	rules.StartingFingers = 3
+
+	player1 := NewPlayer("player 1", "")
+	player2 := NewPlayer("player 2", "")
+	game := NewGame("game", rules)
+
+	if err := errors.Join(game.AddPlayer(player1), game.AddPlayer(player2)); err != nil {
+		t.Fatalf("Game.AddPlayer() error = %v", err)
+	}
+	if err := game.StartGame(); err != nil {
+		t.Fatalf("Game.StartGame() error = %v", err)
+	}
+
+	// 3,3
+	// 1,3
+	if err := game.Attack(true, true); err != nil {
+		t.Fatalf("Game.Attack() error = %v", err)
+	}
+	if got := player2.LeftHand.fingers; got != 1 {
+		t.Errorf("player 2 left hand = %d, want 1", got)
+	}
+	if !player2.LeftHand.Alive() {
+		t.Errorf("player 2 left hand should have rolled over, not died")
+	}
+}
+
+func newStartedGame(t *testing.T, rules Ruleset) *Game {
+	t.Helper()
+	game := NewGame("game", rules)
+	for i := range rules.Players {
+		if err := game.AddPlayer(NewPlayer(fmt.Sprintf("player %d", i+1), "")); err != nil {
+			t.Fatalf("Game.AddPlayer() error = %v", err)
+		}
+	}
+	if err := game.StartGame(); err != nil {
+		t.Fatalf("Game.StartGame() error = %v", err)
+	}
+	return game
+}
+
+func TestGame_LegalMoves(t *testing.T) {
+	game := newStartedGame(t, DefaultRuleset())
+
+	// 1,1 vs 1,1: four attacks and a split of one point from either hand
+	moves := game.LegalMoves()
+	if len(moves) != 6 {
+		t.Fatalf("Game.LegalMoves() = %v, want 6 moves", moves)
+	}
+	for _, m := range moves {
+		clone := newStartedGame(t, DefaultRuleset())
+		if err := clone.Apply(m); err != nil {
+			t.Errorf("Game.Apply(%v) error = %v", m, err)
+		}
+	}
+
+	if err := game.Apply(SplitMove(true, 2)); err == nil {
+		t.Errorf("Game.Apply() splitting more points than the hand holds should fail")
+	}
+	if err := game.Apply(SplitMove(true, 0)); err == nil {
+		t.Errorf("Game.Apply() splitting zero points should fail")
+	}
+	if err := game.Apply(Move{Type: "pass", WithLeft: false, TargetLeft: false, Points: 0}); err == nil {
+		t.Errorf("Game.Apply() with unknown move type should fail")
+	}
+}
+
+func TestGame_LegalMovesSkipsDeadHands(t *testing.T) {
+	game := newStartedGame(t, DefaultRuleset())
+	game.Players[0].LeftHand.Set(0)
+	game.Players[1].RightHand.Set(0)
+
+	for _, m := range game.LegalMoves() {
+		if m.Type == MoveTypeAttack && (m.WithLeft || !m.TargetLeft) {
+			t.Errorf("Game.LegalMoves() returned attack involving a dead hand: %v", m)
+		}
+	}
+}
+
+func TestGame_MultiPlayer(t *testing.T) {
+	rules := DefaultRuleset()
+	rules.Players = 3
+
+	game := NewGame("game", rules)
+	for _, id := range []string{"player 1", "player 2", "player 3"} {
+		if err := game.AddPlayer(NewPlayer(id, "")); err != nil {
+			t.Fatalf("Game.AddPlayer() error = %v", err)
+		}
+	}
+	if err := game.AddPlayer(NewPlayer("player 4", "")); err == nil {
+		t.Errorf("Game.AddPlayer() past the seat limit should fail")
+	}
+	if err := game.StartGame(); err != nil {
+		t.Fatalf("Game.StartGame() error = %v", err)
+	}
+
+	// player 1 may attack either opponent
+	if got := len(game.LegalMoves()); got != 10 {
+		t.Errorf("Game.LegalMoves() has %d moves, want 10", got)
+	}
+	if err := game.Apply(AttackMove(2, true, true)); err != nil {
+		t.Fatalf("Game.Apply() error = %v", err)
+	}
+	if got := game.Players[2].LeftHand.fingers; got != 2 {
+		t.Errorf("player 3 left hand = %d, want 2", got)
+	}
+	if got := game.GetTurn(); got != 1 {
+		t.Errorf("turn = %d, want 1", got)
+	}
+
+	// player 2 knocks out player 3's last hand: 4,1 vs 0,1
+	game.Players[1].LeftHand.Set(4)
+	game.Players[2].LeftHand.Set(0)
+	if err := game.Apply(AttackMove(2, true, false)); err != nil {
+		t.Fatalf("Game.Apply() error = %v", err)
+	}
+	if game.State != GameStateInProgress {
+		t.Fatalf("game state = %s, want %s", game.State, GameStateInProgress)
+	}
+	if got := game.GetTurn(); got != 0 {
+		t.Errorf("turn after an elimination = %d, want 0", got)
+	}
+	if opponents := game.GetOpponents(); len(opponents) != 1 || opponents[0] != game.Players[1] {
+		t.Errorf("Game.GetOpponents() = %v, want player 2", opponents)
+	}
+	if err := game.Apply(AttackMove(2, true, true)); err == nil {
+		t.Errorf("Game.Apply() attacking an eliminated player should fail")
+	}
+
+	// player 1 finishes player 2: 1,1 vs 4,0
+	game.Players[1].RightHand.Set(0)
+	if err := game.Apply(AttackMove(1, true, true)); err != nil {
+		t.Fatalf("Game.Apply() error = %v", err)
+	}
+	if game.State != GameStateFinished || game.Winner != game.Players[0] {
+		t.Errorf("game state = %s winner = %v, want player 1 win", game.State, game.Winner)
+	}
+}
+
+func TestGame_Teams(t *testing.T) {
+	rules := TeamRuleset()
+	rules.TeamTransfers = true
+
+	game := NewGame("game", rules)
+	for _, id := range []string{"player 1", "player 2", "player 3", "player 4"} {
+		if err := game.AddPlayer(NewPlayer(id, "")); err != nil {
+			t.Fatalf("Game.AddPlayer() error = %v", err)
+		}
+	}
+	if err := game.StartGame(); err != nil {
+		t.Fatalf("Game.StartGame() error = %v", err)
+	}
+	for i, p := range game.Players {
+		if p.Team != i%2 {
+			t.Errorf("player %d team = %d, want %d", i+1, p.Team, i%2)
+		}
+	}
+
+	for _, m := range game.LegalMoves() {
+		if m.Type == MoveTypeAttack && m.Target%2 == 0 {
+			t.Errorf("Game.LegalMoves() includes %v against a teammate", m)
+		}
+	}
+	if err := game.Apply(AttackMove(2, true, true)); err == nil {
+		t.Errorf("Game.Apply() attacking a teammate should fail")
+	}
+
+	// player 1 gives a finger to player 3: 0,1 and 2,1
+	if err := game.Apply(TransferMove(2, true, true, 1)); err != nil {
+		t.Fatalf("Game.Apply() error = %v", err)
+	}
+	if got := game.Players[2].LeftHand.fingers; got != 2 {
+		t.Errorf("player 3 left hand = %d, want 2", got)
+	}
+	if err := game.Apply(TransferMove(0, true, true, 1)); err == nil {
+		t.Errorf("Game.Apply() transferring to an opponent should fail")
+	}
+	if err := game.Apply(AttackMove(0, true, false)); err != nil {
+		t.Fatalf("Game.Apply() error = %v", err)
+	}
+
+	// player 3 kills player 4 with their last hand while player 2 is already out
+	game.Players[1].LeftHand.Set(0)
+	game.Players[1].RightHand.Set(0)
+	game.Players[3].LeftHand.Set(0)
+	game.Players[3].RightHand.Set(4)
+	game.Players[2].LeftHand.Set(0)
+	if err := game.Apply(TransferMove(0, false, true, 1)); err == nil {
+		t.Errorf("Game.Apply() giving away the last fingers should fail")
+	}
+	if err := game.Apply(AttackMove(3, false, false)); err != nil {
+		t.Fatalf("Game.Apply() error = %v", err)
+	}
+	if game.State != GameStateFinished || game.Winner == nil || game.Winner.Team != 0 {
+		t.Errorf("game state = %s winner = %v, want team 1 win", game.State, game.Winner)
+	}
+}
+
+func TestGame_TeamsAlternateAfterElimination(t *testing.T) {
+	game := newStartedGame(t, TeamRuleset())
+
+	// player 2 is out, leaving player 4 to move for team 2 every other turn
+	// while players 1 and 3 take turns for team 1
+	if err := game.Forfeit("player 2", ResultResignation); err != nil {
+		t.Fatalf("Game.Forfeit() error = %v", err)
+	}
+	var turns []int
+	for range 6 {
+		turns = append(turns, game.GetTurn())
+		if err := game.Apply(SplitMove(game.currentPlayer().LeftHand.fingers != 0, 1)); err != nil {
+			t.Fatalf("Game.Apply() error = %v", err)
+		}
+	}
+	if want := []int{0, 3, 2, 3, 0, 3}; !slices.Equal(turns, want) {
+		t.Errorf("turns = %v, want %v", turns, want)
+	}
+}
+
+func TestGame_TransfersDisabled(t *testing.T) {
+	game := newStartedGame(t, DefaultRuleset())
+	if err := game.Apply(TransferMove(1, true, true, 1)); err == nil {
+		t.Errorf("Game.Apply() transfer without team transfers should fail")
+	}
+}
+
+func TestGame_LegalMovesFollowSplitRules(t *testing.T) {
+	rules := DefaultRuleset()
+	rules.NoMirror = true
+
+	// 3,2 vs 1,1: 2,3 is a mirror, leaving 1,4 and 4,1
+	game := newStartedGame(t, rules)
+	game.Players[0].LeftHand.Set(3)
+	game.Players[0].RightHand.Set(2)
+
+	var splits []Move
+	for _, m := range game.LegalMoves() {
+		if m.Type == MoveTypeSplit {
+			splits = append(splits, m)
+		}
+	}
+	if want := []Move{SplitMove(true, 2), SplitMove(false, 1)}; !slices.Equal(splits, want) {
+		t.Errorf("Game.LegalMoves() splits = %v, want %v", splits, want)
+	}
+	if err := game.Split(true, 1); err == nil {
+		t.Errorf("Game.Split() mirroring the hands should fail")
+	}
+}
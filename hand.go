@@ -11,7 +11,16 @@ type HandInterface interface {
 	Take(other *Hand, points int) error
 }
 type Hand struct {
-	fingers int // 0–4 (5+ becomes 0)
+	fingers int     // 0 is dead, otherwise below the ruleset's finger limit
+	rules   Ruleset // zero value plays by DefaultRuleset
+}
+
+// ruleset returns the rules this hand is played under
+func (h *Hand) ruleset() Ruleset {
+	if h.rules.Fingers == 0 {
+		return DefaultRuleset()
+	}
+	return h.rules
 }
 
 func (h *Hand) Set(num int) {
@@ -22,32 +31,31 @@ func (h *Hand) Attack(opp *Hand) error {
 	if opp == nil {
 		return errors.New("opponent hand is nil")
 	}
-	if !opp.Alive() {
-		return errors.New("opponent hand is dead")
+	fingers, err := opp.ruleset().attack(h.fingers, opp.fingers)
+	if err != nil {
+		return err
 	}
-	opp.fingers += h.fingers
+	opp.fingers = fingers
 	return nil
 }
 
 func (h *Hand) Alive() bool {
-	return h.fingers < 5
+	return h.ruleset().alive(h.fingers)
 }
 
 func (h *Hand) Take(other *Hand, points int) error {
-	// check if this hand can take that many points without dying
-	if h.fingers+points > 5 {
-		return errors.New("this is more points than this hand can take")
-	}
-	if other.fingers-points < 0 {
-		return errors.New("the other hand does not have enough points")
+	from, to, err := h.ruleset().transfer(other.fingers, h.fingers, points)
+	if err != nil {
+		return err
 	}
-	h.fingers += points
-	other.fingers -= points
+	h.fingers = to
+	other.fingers = from
 	return nil
 }
 
 func NewHand() *Hand {
 	return &Hand{
 		fingers: 1,
+		rules:   DefaultRuleset(),
 	}
 }
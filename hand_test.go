@@ -18,17 +18,41 @@ func TestHand_Attack(t *testing.T) {
 		wantErr bool
 	}{
 		{
-			name: "attack hand with 0 fingers",
+			name: "attack hand with 1 finger",
 			fields: fields{
 				Fingers: 4,
 			},
 			args: args{
 				opp: &Hand{
-					fingers: 0,
+					fingers: 1,
 				},
 			},
 			wantErr: false,
 		},
+		{
+			name: "attack dead hand with 0 fingers",
+			fields: fields{
+				Fingers: 4,
+			},
+			args: args{
+				opp: &Hand{
+					fingers: 0,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "attack with dead hand",
+			fields: fields{
+				Fingers: 0,
+			},
+			args: args{
+				opp: &Hand{
+					fingers: 1,
+				},
+			},
+			wantErr: true,
+		},
 		{
 			name: "attack hand with 5 fingers",
 			fields: fields{
@@ -0,0 +1,150 @@
+package sticks
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// MoveRecord is an entry in a game's append-only move log
+type MoveRecord struct {
+	Move     Move      `json:"move"`
+	PlayerID string    `json:"playerId"`
+	PlayedAt time.Time `json:"playedAt"`
+	clocks   []Clock   // as the mover's turn began, so Undo can put them back
+}
+
+// MoveHistory returns a copy of the moves played so far
+func (g *Game) MoveHistory() []MoveRecord {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+
+	return append([]MoveRecord(nil), g.History...)
+}
+
+// Undo takes back the last move, giving the mover back the time they spent
+// on it. Only practice games in progress may be undone.
+func (g *Game) Undo() error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if !g.Practice {
+		return fmt.Errorf("undo is only available in practice games")
+	}
+	if g.State != GameStateInProgress {
+		return fmt.Errorf("game is not in progress")
+	}
+	if len(g.History) == 0 {
+		return fmt.Errorf("no moves to undo")
+	}
+
+	// rebuild a copy, so the game is left as it was if the replay fails
+	last := g.History[len(g.History)-1]
+	rebuilt := g.clone()
+	if err := rebuilt.replay(g.History[:len(g.History)-1]); err != nil {
+		return err
+	}
+	if last.clocks != nil {
+		rebuilt.Clocks = slices.Clone(last.clocks)
+		rebuilt.turnStarted = time.Now()
+	}
+	g.adopt(rebuilt)
+	g.redo = append(g.redo, last)
+	return nil
+}
+
+// Redo replays the last move taken back by Undo
+func (g *Game) Redo() error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if !g.Practice {
+		return fmt.Errorf("redo is only available in practice games")
+	}
+	if len(g.redo) == 0 {
+		return fmt.Errorf("no moves to redo")
+	}
+	if g.State != GameStateInProgress {
+		return fmt.Errorf("game is not in progress")
+	}
+
+	next := g.redo[len(g.redo)-1]
+	next.clocks = slices.Clone(g.Clocks)
+	mover := g.CurrentTurn
+	if err := g.apply(next.Move); err != nil {
+		return err
+	}
+	g.redo = g.redo[:len(g.redo)-1]
+	g.History = append(g.History, next)
+	g.checkDraw()
+	g.pressClock(mover, time.Now())
+	return nil
+}
+
+// record plays a move for the current player and appends it to the history
+func (g *Game) record(m Move) error {
+	now := time.Now()
+	if g.checkFlag(now) {
+		return ErrFlagged
+	}
+
+	player := g.currentPlayer()
+	mover := g.CurrentTurn
+	clocks := slices.Clone(g.Clocks)
+	if err := g.apply(m); err != nil {
+		return err
+	}
+
+	g.History = append(g.History, MoveRecord{
+		Move:     m,
+		PlayerID: player.ID,
+		PlayedAt: now,
+		clocks:   clocks,
+	})
+	g.redo = nil
+	g.clearDrawOffer()
+	g.checkDraw()
+	g.pressClock(mover, now)
+	return nil
+}
+
+// adopt takes on the state of rebuilt, a clone of the game, without locking.
+// The game keeps its own players and lock, so pointers to them stay good.
+func (g *Game) adopt(rebuilt *Game) {
+	winner := slices.Index(rebuilt.Players, rebuilt.Winner)
+	for i, p := range g.Players {
+		*p = *rebuilt.Players[i]
+	}
+	rebuilt.Players, rebuilt.mutex = g.Players, g.mutex
+	rebuilt.Winner = nil
+	if winner >= 0 {
+		rebuilt.Winner = g.Players[winner]
+	}
+	*g = *rebuilt
+}
+
+// replay resets the game to its starting position and plays records again
+func (g *Game) replay(records []MoveRecord) error {
+	for _, p := range g.Players {
+		p.applyRuleset(g.Rules)
+	}
+	g.State = GameStateInProgress
+	g.CurrentTurn = 0
+	g.teamMovers = [2]int{}
+	g.Winner = nil
+	g.Result = ""
+	g.clearDrawOffer()
+	g.repetitions = map[Position]int{g.position().Canonical(): 1}
+
+	history := make([]MoveRecord, 0, len(records))
+	for _, r := range records {
+		if err := g.apply(r.Move); err != nil {
+			return fmt.Errorf("replaying %v: %w", r.Move, err)
+		}
+		history = append(history, r)
+		g.History = history
+		g.checkDraw()
+	}
+	g.History = history
+	return nil
+}
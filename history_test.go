@@ -0,0 +1,118 @@
+package sticks
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGame_UndoRedo(t *testing.T) {
+	game := newStartedGame(t, DefaultRuleset())
+	game.Practice = true
+
+	moves := []Move{
+		AttackMove(1, true, true),  // 1,1 vs 2,1
+		AttackMove(0, true, true),  // 3,1 vs 2,1
+		AttackMove(1, true, true),  // 3,1 vs 0,1
+		SplitMove(false, 1),        // 3,1 vs 1,0
+		AttackMove(1, false, true), // 3,1 vs 2,0
+	}
+	for _, m := range moves {
+		if err := game.Apply(m); err != nil {
+			t.Fatalf("Game.Apply(%v) error = %v", m, err)
+		}
+	}
+
+	history := game.MoveHistory()
+	if len(history) != len(moves) {
+		t.Fatalf("Game.MoveHistory() has %d records, want %d", len(history), len(moves))
+	}
+	if history[0].PlayerID != "player 1" || history[1].PlayerID != "player 2" {
+		t.Errorf("Game.MoveHistory() recorded wrong players: %v", history)
+	}
+
+	if err := game.Undo(); err != nil {
+		t.Fatalf("Game.Undo() error = %v", err)
+	}
+	if err := game.Undo(); err != nil {
+		t.Fatalf("Game.Undo() error = %v", err)
+	}
+	if got := game.Players[1].LeftHand.fingers; got != 0 {
+		t.Errorf("after undo player 2 left hand = %d, want 0", got)
+	}
+	if got := game.GetTurn(); got != 1 {
+		t.Errorf("after undo turn = %d, want 1", got)
+	}
+
+	if err := game.Redo(); err != nil {
+		t.Fatalf("Game.Redo() error = %v", err)
+	}
+	if got := game.Players[1].LeftHand.fingers; got != 1 {
+		t.Errorf("after redo player 2 left hand = %d, want 1", got)
+	}
+
+	// a fresh move discards the redo stack
+	if err := game.Apply(AttackMove(1, true, true)); err != nil {
+		t.Fatalf("Game.Apply() error = %v", err)
+	}
+	if err := game.Redo(); err == nil {
+		t.Errorf("Game.Redo() after a new move should fail")
+	}
+}
+
+func TestGame_UndoRequiresPractice(t *testing.T) {
+	game := newStartedGame(t, DefaultRuleset())
+	if err := game.Attack(true, true); err != nil {
+		t.Fatalf("Game.Attack() error = %v", err)
+	}
+	if err := game.Undo(); err == nil {
+		t.Errorf("Game.Undo() in a matchmade game should fail")
+	}
+}
+
+func TestGame_UndoRestoresClocks(t *testing.T) {
+	game := NewGame("game", DefaultRuleset())
+	game.Practice = true
+	game.TimeControl = &TimeControl{Base: time.Minute, Increment: 0, PerMove: 0}
+	for _, id := range []string{"player 1", "player 2"} {
+		if err := game.AddPlayer(NewPlayer(id, "")); err != nil {
+			t.Fatalf("Game.AddPlayer() error = %v", err)
+		}
+	}
+	if err := game.StartGame(); err != nil {
+		t.Fatalf("Game.StartGame() error = %v", err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if err := game.Attack(true, true); err != nil {
+		t.Fatalf("Game.Attack() error = %v", err)
+	}
+	if err := game.Undo(); err != nil {
+		t.Fatalf("Game.Undo() error = %v", err)
+	}
+	want := []Clock{{Remaining: time.Minute, Running: true}, {Remaining: time.Minute, Running: false}}
+	if game.Clocks[0] != want[0] || game.Clocks[1] != want[1] {
+		t.Errorf("after undo clocks = %+v, want %+v", game.Clocks, want)
+	}
+}
+
+func TestGame_UndoFailureLeavesGame(t *testing.T) {
+	game := newStartedGame(t, DefaultRuleset())
+	game.Practice = true
+	for _, m := range []Move{AttackMove(1, true, true), AttackMove(0, true, true)} {
+		if err := game.Apply(m); err != nil {
+			t.Fatalf("Game.Apply(%v) error = %v", m, err)
+		}
+	}
+
+	// a history that no longer replays cannot be undone
+	game.History[0].Move = SplitMove(true, 5)
+	before := game.Snapshot()
+	if err := game.Undo(); err == nil {
+		t.Fatalf("Game.Undo() with a broken history should fail")
+	}
+	after := game.Snapshot()
+	if after.MoveCount != before.MoveCount || after.CurrentTurn != before.CurrentTurn ||
+		after.Players[0].LeftHand != before.Players[0].LeftHand || after.Players[1].LeftHand != before.Players[1].LeftHand {
+		t.Errorf("after a failed undo the game = %+v, want %+v", after, before)
+	}
+}
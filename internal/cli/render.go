@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tkahng/sticks"
+)
+
+// Render draws every player's hands, marking the player to move and seat as
+// "you". Pass a seat of -1 when nobody at the terminal owns a seat.
+func Render(s sticks.GameSnapshot, seat int) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "\nmove %d, %s\n", s.MoveCount, strings.ReplaceAll(string(s.State), "_", " "))
+	for i, p := range s.Players {
+		marker := " "
+		if i == s.CurrentTurn && s.State == sticks.GameStateInProgress {
+			marker = ">"
+		}
+		name := p.Name
+		if p.Rating > 0 {
+			name += fmt.Sprintf(" %d", p.Rating)
+		}
+		if i == seat {
+			name += " (you)"
+		}
+		fmt.Fprintf(&sb, "%s P%d %-21s L %s  R %s", marker, i+1, name, renderHand(p.LeftHand), renderHand(p.RightHand))
+		if s.Rules.Teams {
+			fmt.Fprintf(&sb, "  team %d", p.Team+1)
+		}
+		if i < len(s.Clocks) {
+			seconds := s.Clocks[i].RemainingMs / 1000
+			fmt.Fprintf(&sb, "  %d:%02d", seconds/60, seconds%60)
+		}
+		sb.WriteString("\n")
+	}
+	if s.State != sticks.GameStateInProgress && s.Result != "" {
+		fmt.Fprintf(&sb, "result: %s\n", s.Result)
+	}
+	return sb.String()
+}
+
+func renderHand(h sticks.HandSnapshot) string {
+	if !h.Alive {
+		return "x"
+	}
+	return fmt.Sprint(h.Points)
+}
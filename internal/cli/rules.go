@@ -0,0 +1,46 @@
+// Package cli holds what the sticks commands have in common
+package cli
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/tkahng/sticks"
+)
+
+// RulesetFlags registers a flag for every ruleset option on flags and returns
+// a function that builds the ruleset once the flags are parsed
+func RulesetFlags(flags *flag.FlagSet) func() (sticks.Ruleset, error) {
+	variant := flags.String("variant", string(sticks.RuleVariantCutoff), "rule variant: cutoff or rollover")
+	fingers := flags.Int("fingers", 5, "finger limit per hand")
+	starting := flags.Int("starting", 1, "fingers on each hand at the start")
+	players := flags.Int("players", 2, "seats at the table")
+	teams := flags.Bool("teams", false, "two teams of two, seated alternately")
+	transfers := flags.Bool("transfers", false, "teammates may give each other fingers")
+	noRevival := flags.Bool("no-revival", false, "points may not be moved into a dead hand")
+	noMirror := flags.Bool("no-mirror", false, "splits may not just swap the two hands")
+	evenSplits := flags.Bool("even-splits", false, "splits must leave both hands equal")
+	splitOverflow := flags.Bool("split-overflow", false, "points may push a hand past the limit")
+
+	return func() (sticks.Ruleset, error) {
+		rules := sticks.Ruleset{
+			Variant:         sticks.RuleVariant(*variant),
+			Fingers:         *fingers,
+			StartingFingers: *starting,
+			Players:         *players,
+			Teams:           *teams,
+			TeamTransfers:   *transfers,
+			NoRevival:       *noRevival,
+			NoMirror:        *noMirror,
+			EvenSplitsOnly:  *evenSplits,
+			SplitOverflow:   *splitOverflow,
+		}
+		if rules.Teams && *players == 2 {
+			rules.Players = sticks.TeamPlayers
+		}
+		if err := rules.Validate(); err != nil {
+			return sticks.Ruleset{}, fmt.Errorf("invalid rules: %w", err)
+		}
+		return rules, nil
+	}
+}
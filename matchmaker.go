@@ -0,0 +1,308 @@
+package sticks
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"time"
+)
+
+// Matchmaker decides which waiting players play each other. The broker's
+// matchmaking worker is its only user, so implementations need not be safe
+// for concurrent use.
+type Matchmaker interface {
+	// Enqueue adds a request to the back of its queue
+	Enqueue(request *MatchmakingRequest)
+	// Cancel takes a request out of its queue, reporting whether it was
+	// waiting
+	Cancel(request *MatchmakingRequest) bool
+	// Tick drops requests whose context is done, then takes the players of
+	// every match ready at now out of the queue and returns the matches
+	Tick(now time.Time) []Match
+	// Queues returns the requests waiting in each queue, in queue order.
+	// Every queue holds requests for a single ruleset.
+	Queues() [][]*MatchmakingRequest
+}
+
+// Match is a set of players to start a game for
+type Match struct {
+	Rules    Ruleset
+	Requests []*MatchmakingRequest // in seat order
+	Bot      Bot                   // takes the seat after the players, nil for games between people
+}
+
+// queues holds waiting requests in a queue for each ruleset, as every player
+// in a game plays by the same rules
+type queues map[Ruleset][]*MatchmakingRequest
+
+// add puts a request at the back of the queue for its rules
+func (q queues) add(request *MatchmakingRequest) {
+	q[request.Rules] = append(q[request.Rules], request)
+}
+
+// remove takes a request out of its queue, reporting whether it was waiting
+func (q queues) remove(request *MatchmakingRequest) bool {
+	requests := q[request.Rules]
+	i := slices.Index(requests, request)
+	if i < 0 {
+		return false
+	}
+	q.set(request.Rules, slices.Delete(requests, i, i+1))
+	return true
+}
+
+// prune drops requests whose context is done, so nobody is matched against a
+// player who already left
+func (q queues) prune() {
+	for rules, requests := range q {
+		q.set(rules, slices.DeleteFunc(requests, func(request *MatchmakingRequest) bool {
+			if request.Context.Err() == nil {
+				return false
+			}
+			log.Printf("Dropping player %s from the queue: %v", request.Player.ID, request.Context.Err())
+			return true
+		}))
+	}
+}
+
+// take removes matched requests from the queue for rules
+func (q queues) take(rules Ruleset, matched []*MatchmakingRequest) {
+	q.set(rules, slices.DeleteFunc(q[rules], func(request *MatchmakingRequest) bool {
+		return slices.Contains(matched, request)
+	}))
+}
+
+// set replaces the queue for rules, forgetting it once empty
+func (q queues) set(rules Ruleset, requests []*MatchmakingRequest) {
+	if len(requests) == 0 {
+		delete(q, rules)
+		return
+	}
+	q[rules] = requests
+}
+
+// list returns every queue
+func (q queues) list() [][]*MatchmakingRequest {
+	var list [][]*MatchmakingRequest
+	for _, requests := range q {
+		list = append(list, requests)
+	}
+	return list
+}
+
+// FIFOMatchmaker matches players strictly in the order they arrive
+type FIFOMatchmaker struct {
+	queues queues
+}
+
+// NewFIFOMatchmaker returns a first come, first served matchmaker
+func NewFIFOMatchmaker() *FIFOMatchmaker {
+	return &FIFOMatchmaker{queues: queues{}}
+}
+
+func (m *FIFOMatchmaker) Enqueue(request *MatchmakingRequest) {
+	m.queues.add(request)
+}
+
+func (m *FIFOMatchmaker) Cancel(request *MatchmakingRequest) bool {
+	return m.queues.remove(request)
+}
+
+func (m *FIFOMatchmaker) Tick(now time.Time) []Match {
+	m.queues.prune()
+	var matches []Match
+	for rules, requests := range m.queues {
+		for len(requests) >= rules.Players {
+			matches = append(matches, Match{Rules: rules, Requests: slices.Clone(requests[:rules.Players]), Bot: nil})
+			requests = requests[rules.Players:]
+		}
+		m.queues.set(rules, requests)
+	}
+	return matches
+}
+
+func (m *FIFOMatchmaker) Queues() [][]*MatchmakingRequest {
+	return m.queues.list()
+}
+
+// RatingWindow is how far apart in rating matched players may be. The window
+// widens the longer a player waits, so nobody waits forever for an equal.
+type RatingWindow struct {
+	Base   float64 // allowed rating gap on joining the queue
+	Growth float64 // added to the gap for every second waited
+}
+
+// DefaultRatingWindow is the rating window of matchmade games
+func DefaultRatingWindow() RatingWindow {
+	return RatingWindow{Base: 100, Growth: 20}
+}
+
+// At returns the allowed rating gap after waiting for wait
+func (w RatingWindow) At(wait time.Duration) float64 {
+	return w.Base + w.Growth*wait.Seconds()
+}
+
+// RatingMatchmaker matches players whose ratings fit in a rating window
+type RatingMatchmaker struct {
+	queues queues
+	window RatingWindow
+}
+
+// NewRatingMatchmaker returns a matchmaker that pairs players within window
+func NewRatingMatchmaker(window RatingWindow) *RatingMatchmaker {
+	return &RatingMatchmaker{queues: queues{}, window: window}
+}
+
+func (m *RatingMatchmaker) Enqueue(request *MatchmakingRequest) {
+	m.queues.add(request)
+}
+
+func (m *RatingMatchmaker) Cancel(request *MatchmakingRequest) bool {
+	return m.queues.remove(request)
+}
+
+func (m *RatingMatchmaker) Tick(now time.Time) []Match {
+	m.queues.prune()
+	var matches []Match
+	for rules := range m.queues {
+		for {
+			matched := m.find(rules, now)
+			if matched == nil {
+				break
+			}
+			m.queues.take(rules, matched)
+			matches = append(matches, Match{Rules: rules, Requests: matched, Bot: nil})
+		}
+	}
+	return matches
+}
+
+// find returns the first full set of players for rules whose ratings fit in
+// a rating window, or nil if there is none. Each player in turn, from the
+// longest waiting, is matched with the next players within their window,
+// which is the widest of the set.
+func (m *RatingMatchmaker) find(rules Ruleset, now time.Time) []*MatchmakingRequest {
+	requests := m.queues[rules]
+	for i, first := range requests {
+		gap := m.window.At(now.Sub(first.QueuedAt))
+		matched := []*MatchmakingRequest{first}
+		for _, request := range requests[i+1:] {
+			if len(matched) == rules.Players {
+				break
+			}
+			if math.Abs(request.Rating-first.Rating) <= gap {
+				matched = append(matched, request)
+			}
+		}
+		if len(matched) == rules.Players {
+			return matched
+		}
+	}
+	return nil
+}
+
+func (m *RatingMatchmaker) Queues() [][]*MatchmakingRequest {
+	return m.queues.list()
+}
+
+// BucketedMatchmaker keeps a separate matchmaker for each bucket, so players
+// are only matched with others in the same bucket
+type BucketedMatchmaker struct {
+	bucket        func(*MatchmakingRequest) string
+	newMatchmaker func() Matchmaker
+	buckets       map[string]Matchmaker
+}
+
+// NewBucketedMatchmaker returns a matchmaker that puts each request in the
+// bucket named by bucket, matching within it with a matchmaker from
+// newMatchmaker
+func NewBucketedMatchmaker(bucket func(*MatchmakingRequest) string, newMatchmaker func() Matchmaker) *BucketedMatchmaker {
+	return &BucketedMatchmaker{
+		bucket:        bucket,
+		newMatchmaker: newMatchmaker,
+		buckets:       make(map[string]Matchmaker),
+	}
+}
+
+// ByRegion buckets requests by the player's region
+func ByRegion(request *MatchmakingRequest) string {
+	return request.Region
+}
+
+// ByRuleset buckets requests by the rules asked for
+func ByRuleset(request *MatchmakingRequest) string {
+	return fmt.Sprintf("%+v", request.Rules)
+}
+
+func (m *BucketedMatchmaker) Enqueue(request *MatchmakingRequest) {
+	key := m.bucket(request)
+	bucket, ok := m.buckets[key]
+	if !ok {
+		bucket = m.newMatchmaker()
+		m.buckets[key] = bucket
+	}
+	bucket.Enqueue(request)
+}
+
+func (m *BucketedMatchmaker) Cancel(request *MatchmakingRequest) bool {
+	bucket, ok := m.buckets[m.bucket(request)]
+	return ok && bucket.Cancel(request)
+}
+
+func (m *BucketedMatchmaker) Tick(now time.Time) []Match {
+	var matches []Match
+	for key, bucket := range m.buckets {
+		matches = append(matches, bucket.Tick(now)...)
+		if len(bucket.Queues()) == 0 {
+			delete(m.buckets, key)
+		}
+	}
+	return matches
+}
+
+func (m *BucketedMatchmaker) Queues() [][]*MatchmakingRequest {
+	var list [][]*MatchmakingRequest
+	for _, bucket := range m.buckets {
+		list = append(list, bucket.Queues()...)
+	}
+	return list
+}
+
+// BotBackfill wraps a matchmaker, giving the players at the front of a queue
+// a bot for the last seat once they have waited long enough and only that
+// seat is missing
+type BotBackfill struct {
+	Matchmaker
+	after      time.Duration
+	difficulty BotDifficulty
+}
+
+// NewBotBackfill returns m with bots of difficulty filling in for the last
+// player after after
+func NewBotBackfill(m Matchmaker, after time.Duration, difficulty BotDifficulty) *BotBackfill {
+	return &BotBackfill{Matchmaker: m, after: after, difficulty: difficulty}
+}
+
+func (b *BotBackfill) Tick(now time.Time) []Match {
+	matches := b.Matchmaker.Tick(now)
+	for _, queue := range b.Matchmaker.Queues() {
+		rules := queue[0].Rules
+		seats := rules.Players - 1
+		if len(queue) < seats || now.Sub(queue[0].QueuedAt) < b.after {
+			continue
+		}
+
+		bot, err := NewBot(b.difficulty, rules)
+		if err != nil {
+			log.Printf("No %s bot to backfill %d player games: %v", b.difficulty, rules.Players, err)
+			continue
+		}
+		requests := slices.Clone(queue[:seats])
+		for _, request := range requests {
+			b.Matchmaker.Cancel(request)
+		}
+		matches = append(matches, Match{Rules: rules, Requests: requests, Bot: bot})
+	}
+	return matches
+}
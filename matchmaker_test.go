@@ -0,0 +1,135 @@
+package sticks
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+// queueRequest makes a request that joined the queue at queuedAt
+func queueRequest(id string, rules Ruleset, rating float64, region string, queuedAt time.Time) *MatchmakingRequest {
+	return &MatchmakingRequest{
+		Player:   NewPlayer(id, ""),
+		Rules:    rules,
+		Context:  context.Background(),
+		Rating:   rating,
+		Region:   region,
+		Response: make(chan *MatchmakingResponse, 1),
+		Status:   make(chan QueueStatus, 1),
+		QueuedAt: queuedAt,
+	}
+}
+
+// matchedIDs returns the player IDs of each match
+func matchedIDs(matches []Match) [][]string {
+	var ids [][]string
+	for _, m := range matches {
+		var players []string
+		for _, r := range m.Requests {
+			players = append(players, r.Player.ID)
+		}
+		ids = append(ids, players)
+	}
+	return ids
+}
+
+func TestFIFOMatchmaker(t *testing.T) {
+	rules := DefaultRuleset()
+	start := time.Now()
+	gone, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	m := NewFIFOMatchmaker()
+	alice := queueRequest("alice", rules, 1500, "", start)
+	ghost := queueRequest("ghost", rules, 1500, "", start)
+	ghost.Context = gone
+	carol := queueRequest("carol", rules, 1500, "", start)
+	for _, r := range []*MatchmakingRequest{alice, ghost, queueRequest("bob", rules, 2400, "", start), carol} {
+		m.Enqueue(r)
+	}
+	if !m.Cancel(carol) || m.Cancel(carol) {
+		t.Errorf("FIFOMatchmaker.Cancel() should only find carol once")
+	}
+
+	// ratings do not matter, and players who left are skipped
+	got := matchedIDs(m.Tick(start))
+	if want := [][]string{{"alice", "bob"}}; len(got) != 1 || !slices.Equal(got[0], want[0]) {
+		t.Errorf("FIFOMatchmaker.Tick() = %v, want %v", got, want)
+	}
+	if queues := m.Queues(); len(queues) != 0 {
+		t.Errorf("FIFOMatchmaker.Queues() = %v, want none left", queues)
+	}
+}
+
+func TestRatingMatchmaker(t *testing.T) {
+	rules := DefaultRuleset()
+	start := time.Now()
+	m := NewRatingMatchmaker(RatingWindow{Base: 100, Growth: 10})
+	for _, r := range []*MatchmakingRequest{
+		queueRequest("strong", rules, 1900, "", start),
+		queueRequest("weak", rules, 1400, "", start),
+		queueRequest("near", rules, 1480, "", start),
+	} {
+		m.Enqueue(r)
+	}
+
+	// the weaker pair are within 100 of each other, the strong player waits
+	got := matchedIDs(m.Tick(start))
+	if len(got) != 1 || !slices.Equal(got[0], []string{"weak", "near"}) {
+		t.Fatalf("RatingMatchmaker.Tick() = %v, want weak and near", got)
+	}
+
+	m.Enqueue(queueRequest("newcomer", rules, 1600, "", start.Add(5*time.Second)))
+	if got := m.Tick(start.Add(10 * time.Second)); got != nil {
+		t.Errorf("RatingMatchmaker.Tick() = %v, want no match for a 300 point gap after 10s", matchedIDs(got))
+	}
+	// after 20s the strong player's window reaches 300
+	got = matchedIDs(m.Tick(start.Add(20 * time.Second)))
+	if len(got) != 1 || !slices.Equal(got[0], []string{"strong", "newcomer"}) {
+		t.Errorf("RatingMatchmaker.Tick() = %v, want strong and newcomer", got)
+	}
+}
+
+func TestBucketedMatchmaker(t *testing.T) {
+	rules := DefaultRuleset()
+	start := time.Now()
+	m := NewBucketedMatchmaker(ByRegion, func() Matchmaker { return NewFIFOMatchmaker() })
+	for _, r := range []*MatchmakingRequest{
+		queueRequest("eu 1", rules, 1500, "eu", start),
+		queueRequest("us 1", rules, 1500, "us", start),
+		queueRequest("eu 2", rules, 1500, "eu", start),
+	} {
+		m.Enqueue(r)
+	}
+
+	got := matchedIDs(m.Tick(start))
+	if len(got) != 1 || !slices.Equal(got[0], []string{"eu 1", "eu 2"}) {
+		t.Errorf("BucketedMatchmaker.Tick() = %v, want the two eu players", got)
+	}
+	if queues := m.Queues(); len(queues) != 1 || queues[0][0].Player.ID != "us 1" {
+		t.Errorf("BucketedMatchmaker.Queues() = %v, want us 1 still waiting", queues)
+	}
+}
+
+func TestBotBackfill(t *testing.T) {
+	start := time.Now()
+	m := NewBotBackfill(NewFIFOMatchmaker(), 10*time.Second, BotDifficultyEasy)
+	m.Enqueue(queueRequest("alice", DefaultRuleset(), 1500, "", start))
+	teams := TeamRuleset()
+	for _, id := range []string{"bob", "carol"} {
+		m.Enqueue(queueRequest(id, teams, 1500, "", start))
+	}
+
+	if got := m.Tick(start.Add(5 * time.Second)); got != nil {
+		t.Errorf("BotBackfill.Tick() = %v, want no match before the wait is up", matchedIDs(got))
+	}
+	// alice gets a bot, the team game is still two players short
+	got := m.Tick(start.Add(10 * time.Second))
+	if len(got) != 1 || got[0].Bot == nil || !slices.Equal(matchedIDs(got)[0], []string{"alice"}) {
+		t.Fatalf("BotBackfill.Tick() = %v, want alice against a bot", matchedIDs(got))
+	}
+	if queues := m.Queues(); len(queues) != 1 || len(queues[0]) != 2 {
+		t.Errorf("BotBackfill.Queues() = %v, want the team players still waiting", queues)
+	}
+}
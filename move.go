@@ -0,0 +1,77 @@
+package sticks
+
+import "fmt"
+
+// MoveType identifies the kind of turn a player takes
+type MoveType string
+
+const (
+	MoveTypeAttack   MoveType = "attack"
+	MoveTypeSplit    MoveType = "split"
+	MoveTypeTransfer MoveType = "transfer"
+)
+
+// Move is a single turn taken by the current player
+type Move struct {
+	Type       MoveType `json:"type"`
+	WithLeft   bool     `json:"withLeft"`   // attacking hand, or the hand giving points in a split
+	Target     int      `json:"target"`     // seat of the player being attacked, or of the teammate receiving a transfer
+	TargetLeft bool     `json:"targetLeft"` // hand being attacked, or receiving a transfer
+	Points     int      `json:"points"`     // points moved to the other hand in a split, or to a teammate
+}
+
+// AttackMove returns a move attacking the targetLeft hand of the player at seat
+// target with the current player's withLeft hand
+func AttackMove(target int, withLeft, targetLeft bool) Move {
+	return Move{
+		Type:       MoveTypeAttack,
+		WithLeft:   withLeft,
+		Target:     target,
+		TargetLeft: targetLeft,
+		Points:     0,
+	}
+}
+
+// SplitMove returns a move taking points from the current player's fromLeft
+// hand and giving them to their other hand
+func SplitMove(fromLeft bool, points int) Move {
+	return Move{
+		Type:       MoveTypeSplit,
+		WithLeft:   fromLeft,
+		Target:     0,
+		TargetLeft: !fromLeft,
+		Points:     points,
+	}
+}
+
+// TransferMove returns a move giving points from the current player's fromLeft
+// hand to the toLeft hand of their teammate at seat target
+func TransferMove(target int, fromLeft, toLeft bool, points int) Move {
+	return Move{
+		Type:       MoveTypeTransfer,
+		WithLeft:   fromLeft,
+		Target:     target,
+		TargetLeft: toLeft,
+		Points:     points,
+	}
+}
+
+func (m Move) String() string {
+	switch m.Type {
+	case MoveTypeAttack:
+		return fmt.Sprintf("attack %s -> player %d %s", handName(m.WithLeft), m.Target+1, handName(m.TargetLeft))
+	case MoveTypeSplit:
+		return fmt.Sprintf("split %d from %s", m.Points, handName(m.WithLeft))
+	case MoveTypeTransfer:
+		return fmt.Sprintf("transfer %d from %s -> player %d %s", m.Points, handName(m.WithLeft), m.Target+1, handName(m.TargetLeft))
+	default:
+		return fmt.Sprintf("unknown move %q", m.Type)
+	}
+}
+
+func handName(isLeft bool) string {
+	if isLeft {
+		return "left"
+	}
+	return "right"
+}
@@ -0,0 +1,489 @@
+package sticks
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Game notation is a plain text format modelled on chess PGN. A header of
+// [Tag "value"] pairs describes the players, ruleset, date and result, and is
+// followed by the movetext: one numbered token per move and a result token.
+//
+//	[Game "game_1"]
+//	[Date "2025.06.01"]
+//	[Player1 "Alice"]
+//	[Player1ID "player1"]
+//	[Player2 "Bob"]
+//	[Player2ID "player2"]
+//	[Variant "cutoff"]
+//	[Fingers "5"]
+//	[StartingFingers "1"]
+//	[Players "2"]
+//	[Result "1-0"]
+//	[Termination "elimination"]
+//
+//	1. Lx2L 2. Lx1L 3. Lx2L 1-0
+//
+// Optional rules such as NoMirror or Teams get a "true" tag when they are on
+// and are left out otherwise.
+//
+// Attacks are written as the attacking hand, "x", the defender's seat
+// numbered from 1 and the defending hand (Lx2R attacks player 2's right hand
+// with the left). The seat may be left out to attack the next opponent in turn
+// order. Splits are written as the hand giving points followed by the number
+// of points (R2 moves two points from the right hand to the left). Transfers
+// between teammates are written as the giving hand, ">", the teammate's seat,
+// the receiving hand and the number of points (L>3R2).
+//
+// Two player results are written 1-0 or 0-1 as in chess; with more players
+// the winner is written by seat, as P3, and in team games by team, as T1.
+
+const (
+	ResultPlayer1Wins = "1-0"
+	ResultPlayer2Wins = "0-1"
+	ResultDraw        = "1/2-1/2"
+	ResultUnfinished  = "*"
+)
+
+const notationDateLayout = "2006.01.02"
+
+// FormatMove returns the notation token for a move
+func FormatMove(m Move) string {
+	switch m.Type {
+	case MoveTypeAttack:
+		seat := ""
+		if m.Target >= 0 {
+			seat = strconv.Itoa(m.Target + 1)
+		}
+		return handLetter(m.WithLeft) + "x" + seat + handLetter(m.TargetLeft)
+	case MoveTypeSplit:
+		return handLetter(m.WithLeft) + strconv.Itoa(m.Points)
+	case MoveTypeTransfer:
+		return handLetter(m.WithLeft) + ">" + strconv.Itoa(m.Target+1) + handLetter(m.TargetLeft) + strconv.Itoa(m.Points)
+	default:
+		return "?"
+	}
+}
+
+// ParseMove parses a notation token into a move. Attacks that leave out the
+// defender's seat have a Target of -1; Game.ParseMove fills it in.
+func ParseMove(token string) (Move, error) {
+	token = strings.ToUpper(strings.TrimSpace(token))
+	if len(token) < 2 {
+		return Move{}, fmt.Errorf("invalid move %q", token)
+	}
+
+	withLeft, err := parseHandLetter(token[0])
+	if err != nil {
+		return Move{}, fmt.Errorf("invalid move %q: %w", token, err)
+	}
+
+	if token[1] == 'X' {
+		if len(token) < 3 {
+			return Move{}, fmt.Errorf("invalid attack %q", token)
+		}
+		target := -1
+		if seat := token[2 : len(token)-1]; seat != "" {
+			n, err := strconv.Atoi(seat)
+			if err != nil || n < 1 {
+				return Move{}, fmt.Errorf("invalid attack %q", token)
+			}
+			target = n - 1
+		}
+		targetLeft, err := parseHandLetter(token[len(token)-1])
+		if err != nil {
+			return Move{}, fmt.Errorf("invalid attack %q: %w", token, err)
+		}
+		return AttackMove(target, withLeft, targetLeft), nil
+	}
+
+	if token[1] == '>' {
+		i := strings.IndexAny(token[2:], "LR") + 2
+		if i < 3 {
+			return Move{}, fmt.Errorf("invalid transfer %q", token)
+		}
+		seat, err := strconv.Atoi(token[2:i])
+		if err != nil || seat < 1 {
+			return Move{}, fmt.Errorf("invalid transfer %q", token)
+		}
+		toLeft, err := parseHandLetter(token[i])
+		if err != nil {
+			return Move{}, fmt.Errorf("invalid transfer %q: %w", token, err)
+		}
+		points, err := strconv.Atoi(token[i+1:])
+		if err != nil {
+			return Move{}, fmt.Errorf("invalid transfer %q", token)
+		}
+		return TransferMove(seat-1, withLeft, toLeft, points), nil
+	}
+
+	points, err := strconv.Atoi(token[1:])
+	if err != nil {
+		return Move{}, fmt.Errorf("invalid split %q", token)
+	}
+	return SplitMove(withLeft, points), nil
+}
+
+// ParseMove parses a notation token into a move for the current player,
+// aiming seatless attacks at the next opponent in turn order
+func (g *Game) ParseMove(token string) (Move, error) {
+	m, err := ParseMove(token)
+	if err != nil {
+		return Move{}, err
+	}
+	if m.Type == MoveTypeAttack && m.Target < 0 {
+		g.mutex.RLock()
+		m.Target = g.nextOpponent()
+		g.mutex.RUnlock()
+	}
+	return m, nil
+}
+
+// WriteNotation writes a game's header and move history in game notation
+func WriteNotation(w io.Writer, g *Game) error {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+
+	tags := []notationTag{
+		{name: "Game", value: g.ID},
+		{name: "Date", value: g.CreatedAt.Format(notationDateLayout)},
+	}
+	for i, p := range g.Players {
+		tags = append(tags,
+			notationTag{name: fmt.Sprintf("Player%d", i+1), value: p.Name},
+			notationTag{name: fmt.Sprintf("Player%dID", i+1), value: p.ID},
+		)
+	}
+	tags = append(tags,
+		notationTag{name: "Variant", value: string(g.Rules.Variant)},
+		notationTag{name: "Fingers", value: strconv.Itoa(g.Rules.Fingers)},
+		notationTag{name: "StartingFingers", value: strconv.Itoa(g.Rules.StartingFingers)},
+		notationTag{name: "Players", value: strconv.Itoa(g.Rules.Players)},
+	)
+	rules := g.Rules
+	for _, flag := range notationFlags(&rules) {
+		if *flag.field {
+			tags = append(tags, notationTag{name: flag.name, value: "true"})
+		}
+	}
+	if g.MaxMoves > 0 {
+		tags = append(tags, notationTag{name: "MaxMoves", value: strconv.Itoa(g.MaxMoves)})
+	}
+	tags = append(tags, notationTag{name: "Result", value: g.result()})
+	if g.Result != "" {
+		tags = append(tags, notationTag{name: "Termination", value: string(g.Result)})
+	}
+
+	var sb strings.Builder
+	for _, tag := range tags {
+		fmt.Fprintf(&sb, "[%s %s]\n", tag.name, strconv.Quote(tag.value))
+	}
+	sb.WriteString("\n")
+
+	tokens := make([]string, 0, len(g.History)+1)
+	for i, r := range g.History {
+		tokens = append(tokens, fmt.Sprintf("%d. %s", i+1, FormatMove(r.Move)))
+	}
+	tokens = append(tokens, g.result())
+
+	width := 0
+	for i, token := range tokens {
+		if i > 0 {
+			if width+1+len(token) > 80 {
+				sb.WriteString("\n")
+				width = 0
+			} else {
+				sb.WriteString(" ")
+				width++
+			}
+		}
+		sb.WriteString(token)
+		width += len(token)
+	}
+	sb.WriteString("\n")
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// ReadNotation parses a game written in game notation and replays its moves,
+// returning the game in its final state
+func ReadNotation(r io.Reader) (*Game, error) {
+	tags := map[string]string{}
+	var tokens []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "["):
+			tag, err := parseNotationTag(line)
+			if err != nil {
+				return nil, err
+			}
+			tags[tag.name] = tag.value
+		default:
+			tokens = append(tokens, strings.Fields(line)...)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	rules, err := rulesetFromTags(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	game := NewGame(tags["Game"], rules)
+	if maxMoves, ok := tags["MaxMoves"]; ok {
+		n, err := strconv.Atoi(maxMoves)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid MaxMoves tag %q", maxMoves)
+		}
+		game.MaxMoves = n
+	}
+	if date, ok := tags["Date"]; ok {
+		createdAt, err := time.Parse(notationDateLayout, date)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Date tag %q", date)
+		}
+		game.CreatedAt = createdAt
+	}
+	for i := 1; i <= rules.Players; i++ {
+		id := tags[fmt.Sprintf("Player%dID", i)]
+		name := tags[fmt.Sprintf("Player%d", i)]
+		if err := game.AddPlayer(NewPlayer(id, name)); err != nil {
+			return nil, err
+		}
+	}
+	if err := game.StartGame(); err != nil {
+		return nil, err
+	}
+
+	result := ""
+	for _, token := range tokens {
+		switch {
+		case strings.HasSuffix(token, "."):
+			// move number
+			continue
+		case isResultToken(token):
+			result = token
+			continue
+		}
+
+		m, err := game.ParseMove(token)
+		if err != nil {
+			return nil, err
+		}
+		if err := game.Apply(m); err != nil {
+			return nil, fmt.Errorf("move %s: %w", token, err)
+		}
+	}
+
+	if tag, ok := tags["Result"]; ok && result == "" {
+		result = tag
+	}
+	if termination, ok := tags["Termination"]; ok {
+		if err := game.terminate(ResultReason(termination), result); err != nil {
+			return nil, err
+		}
+	}
+	if result != "" && result != game.result() {
+		return nil, fmt.Errorf("result %s does not match the moves played (%s)", result, game.result())
+	}
+
+	return game, nil
+}
+
+// terminate ends a replayed game for reason. Eliminations, repetitions and
+// the move limit come from the moves themselves and are only checked. Any
+// other reason ends the game after the last move, with result naming the
+// winners, without locking.
+func (g *Game) terminate(reason ResultReason, result string) error {
+	switch reason {
+	case ResultElimination, ResultRepetition, ResultMoveLimit:
+		if g.Result != reason {
+			return fmt.Errorf("termination %s does not match the moves played (%s)", reason, g.Result)
+		}
+		return nil
+	}
+	if g.State != GameStateInProgress {
+		return fmt.Errorf("termination %s after the game ended by %s", reason, g.Result)
+	}
+
+	switch reason {
+	case ResultAborted:
+		if len(g.History) > 0 {
+			return fmt.Errorf("a game can only be aborted before the first move")
+		}
+		g.State = GameStateAborted
+		g.Result = reason
+	case ResultAgreement, ResultTimeout:
+		g.draw(reason)
+	case ResultFlag, ResultAbandoned, ResultResignation:
+		// everyone the result does not name was knocked out
+		for seat, p := range g.Players {
+			if p.Alive() && !g.won(result, seat) && g.State == GameStateInProgress {
+				g.eliminate(seat, reason)
+			}
+		}
+		if g.State != GameStateFinished {
+			return fmt.Errorf("result %s does not name the winner of a game ended by %s", result, reason)
+		}
+	default:
+		return fmt.Errorf("unknown termination %q", reason)
+	}
+	return nil
+}
+
+// won reports whether the player at seat is among the winners named by a
+// result token without locking
+func (g *Game) won(result string, seat int) bool {
+	switch result {
+	case ResultPlayer1Wins:
+		return seat == 0
+	case ResultPlayer2Wins:
+		return seat == 1 && len(g.Players) == 2
+	}
+	if !isResultToken(result) || (result[0] != 'P' && result[0] != 'T') {
+		return false
+	}
+	n, _ := strconv.Atoi(result[1:])
+	if result[0] == 'T' {
+		return g.Rules.Teams && g.Players[seat].Team == n-1
+	}
+	return seat == n-1
+}
+
+type notationTag struct {
+	name  string
+	value string
+}
+
+// result returns the notation result token for the game
+func (g *Game) result() string {
+	if g.State == GameStateDraw {
+		return ResultDraw
+	}
+	if g.State != GameStateFinished || g.Winner == nil {
+		return ResultUnfinished
+	}
+	if g.Rules.Teams {
+		return fmt.Sprintf("T%d", g.Winner.Team+1)
+	}
+	seat := slices.Index(g.Players, g.Winner)
+	if len(g.Players) > 2 {
+		return fmt.Sprintf("P%d", seat+1)
+	}
+	if seat == 0 {
+		return ResultPlayer1Wins
+	}
+	return ResultPlayer2Wins
+}
+
+func isResultToken(token string) bool {
+	switch token {
+	case ResultPlayer1Wins, ResultPlayer2Wins, ResultDraw, ResultUnfinished:
+		return true
+	}
+	if len(token) < 2 || (token[0] != 'P' && token[0] != 'T') {
+		return false
+	}
+	_, err := strconv.Atoi(token[1:])
+	return err == nil
+}
+
+func parseNotationTag(line string) (notationTag, error) {
+	if !strings.HasSuffix(line, "]") {
+		return notationTag{}, fmt.Errorf("invalid tag %q", line)
+	}
+	name, value, ok := strings.Cut(line[1:len(line)-1], " ")
+	if !ok {
+		return notationTag{}, fmt.Errorf("invalid tag %q", line)
+	}
+	value, err := strconv.Unquote(strings.TrimSpace(value))
+	if err != nil {
+		return notationTag{}, fmt.Errorf("invalid tag value in %q", line)
+	}
+	return notationTag{name: name, value: value}, nil
+}
+
+// rulesetFromTags reads the ruleset tags, using the default rules for any tag
+// that is missing
+func rulesetFromTags(tags map[string]string) (Ruleset, error) {
+	rules := DefaultRuleset()
+	if variant, ok := tags["Variant"]; ok {
+		rules.Variant = RuleVariant(variant)
+	}
+	for name, field := range map[string]*int{
+		"Fingers":         &rules.Fingers,
+		"StartingFingers": &rules.StartingFingers,
+		"Players":         &rules.Players,
+	} {
+		value, ok := tags[name]
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return Ruleset{}, fmt.Errorf("invalid %s tag %q", name, value)
+		}
+		*field = n
+	}
+	for _, flag := range notationFlags(&rules) {
+		value, ok := tags[flag.name]
+		if !ok {
+			continue
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return Ruleset{}, fmt.Errorf("invalid %s tag %q", flag.name, value)
+		}
+		*flag.field = b
+	}
+	return rules, rules.Validate()
+}
+
+// notationFlag is an optional ruleset tag, only written when the rule is on
+type notationFlag struct {
+	name  string
+	field *bool
+}
+
+// notationFlags returns the optional ruleset tags in the order they are written
+func notationFlags(rules *Ruleset) []notationFlag {
+	return []notationFlag{
+		{name: "Teams", field: &rules.Teams},
+		{name: "TeamTransfers", field: &rules.TeamTransfers},
+		{name: "NoRevival", field: &rules.NoRevival},
+		{name: "NoMirror", field: &rules.NoMirror},
+		{name: "EvenSplitsOnly", field: &rules.EvenSplitsOnly},
+		{name: "SplitOverflow", field: &rules.SplitOverflow},
+	}
+}
+
+func handLetter(isLeft bool) string {
+	if isLeft {
+		return "L"
+	}
+	return "R"
+}
+
+func parseHandLetter(c byte) (bool, error) {
+	switch c {
+	case 'L':
+		return true, nil
+	case 'R':
+		return false, nil
+	default:
+		return false, fmt.Errorf("unknown hand %q", c)
+	}
+}
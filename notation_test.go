@@ -0,0 +1,290 @@
+package sticks
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseMove(t *testing.T) {
+	tests := []struct {
+		token   string
+		want    Move
+		wantErr bool
+	}{
+		{token: "Lx2R", want: AttackMove(1, true, false)},
+		{token: "rx1l", want: AttackMove(0, false, true)},
+		{token: "LxR", want: AttackMove(-1, true, false)},
+		{token: "Lx0R", wantErr: true},
+		{token: "L2", want: SplitMove(true, 2)},
+		{token: "R1", want: SplitMove(false, 1)},
+		{token: "L>3R2", want: TransferMove(2, true, false, 2)},
+		{token: "R>L1", wantErr: true},
+		{token: "L>3R", wantErr: true},
+		{token: "Lx", wantErr: true},
+		{token: "Q1", wantErr: true},
+		{token: "Lone", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.token, func(t *testing.T) {
+			got, err := ParseMove(tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseMove() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("ParseMove() = %v, want %v", got, tt.want)
+			}
+			if err == nil && !strings.EqualFold(FormatMove(got), tt.token) {
+				t.Errorf("FormatMove() = %s, want %s", FormatMove(got), tt.token)
+			}
+		})
+	}
+}
+
+func TestNotation_RoundTrip(t *testing.T) {
+	rules := DefaultRuleset()
+	rules.Variant = RuleVariantRollover
+
+	game := NewGame("round trip", rules)
+	if err := game.AddPlayer(NewPlayer("p1", `Alice "the quick"`)); err != nil {
+		t.Fatalf("Game.AddPlayer() error = %v", err)
+	}
+	if err := game.AddPlayer(NewPlayer("p2", "Bob")); err != nil {
+		t.Fatalf("Game.AddPlayer() error = %v", err)
+	}
+	if err := game.StartGame(); err != nil {
+		t.Fatalf("Game.StartGame() error = %v", err)
+	}
+	for _, m := range []Move{AttackMove(1, true, true), SplitMove(true, 1), AttackMove(1, false, false)} {
+		if err := game.Apply(m); err != nil {
+			t.Fatalf("Game.Apply(%v) error = %v", m, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := WriteNotation(&buf, game); err != nil {
+		t.Fatalf("WriteNotation() error = %v", err)
+	}
+
+	decoded, err := ReadNotation(&buf)
+	if err != nil {
+		t.Fatalf("ReadNotation() error = %v\n%s", err, buf.String())
+	}
+	if decoded.ID != game.ID || decoded.Rules != game.Rules {
+		t.Errorf("ReadNotation() header = %s %v, want %s %v", decoded.ID, decoded.Rules, game.ID, game.Rules)
+	}
+	if decoded.Players[0].Name != game.Players[0].Name || decoded.Players[1].ID != game.Players[1].ID {
+		t.Errorf("ReadNotation() players = %v %v", decoded.Players[0], decoded.Players[1])
+	}
+	if len(decoded.History) != len(game.History) {
+		t.Fatalf("ReadNotation() replayed %d moves, want %d", len(decoded.History), len(game.History))
+	}
+	for i, p := range decoded.Players {
+		want := game.Players[i]
+		if p.LeftHand.fingers != want.LeftHand.fingers || p.RightHand.fingers != want.RightHand.fingers {
+			t.Errorf("ReadNotation() player %d hands = %d,%d want %d,%d", i+1,
+				p.LeftHand.fingers, p.RightHand.fingers, want.LeftHand.fingers, want.RightHand.fingers)
+		}
+	}
+	if decoded.GetTurn() != game.GetTurn() {
+		t.Errorf("ReadNotation() turn = %d, want %d", decoded.GetTurn(), game.GetTurn())
+	}
+}
+
+func TestReadNotation_ResultMismatch(t *testing.T) {
+	text := `[Game "g"]
+[Player1 "a"]
+[Player2 "b"]
+
+1. LxL 2. LxL 3. LxL 4. RxL 5. LxR 0-1
+`
+	if _, err := ReadNotation(strings.NewReader(text)); err == nil {
+		t.Errorf("ReadNotation() with the wrong result should fail")
+	}
+
+	text = strings.Replace(text, "0-1", "1-0", 1)
+	game, err := ReadNotation(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("ReadNotation() error = %v", err)
+	}
+	if game.State != GameStateFinished || game.Winner != game.Players[0] {
+		t.Errorf("ReadNotation() state = %s winner = %v, want player 1 win", game.State, game.Winner)
+	}
+}
+
+func TestNotation_MultiPlayer(t *testing.T) {
+	rules := DefaultRuleset()
+	rules.Players = 3
+
+	game := NewGame("game", rules)
+	for _, id := range []string{"player 1", "player 2", "player 3"} {
+		if err := game.AddPlayer(NewPlayer(id, "")); err != nil {
+			t.Fatalf("Game.AddPlayer() error = %v", err)
+		}
+	}
+	if err := game.StartGame(); err != nil {
+		t.Fatalf("Game.StartGame() error = %v", err)
+	}
+	for _, m := range []Move{AttackMove(2, true, true), AttackMove(0, false, true), SplitMove(true, 1)} {
+		if err := game.Apply(m); err != nil {
+			t.Fatalf("Game.Apply(%v) error = %v", m, err)
+		}
+	}
+
+	var buf strings.Builder
+	if err := WriteNotation(&buf, game); err != nil {
+		t.Fatalf("WriteNotation() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "1. Lx3L 2. Rx1L 3. L1") {
+		t.Errorf("WriteNotation() movetext = %q", buf.String())
+	}
+
+	decoded, err := ReadNotation(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatalf("ReadNotation() error = %v", err)
+	}
+	if len(decoded.Players) != 3 || decoded.Players[2].ID != "player 3" {
+		t.Errorf("ReadNotation() players = %v", decoded.Players)
+	}
+	if decoded.position() != game.position() {
+		t.Errorf("ReadNotation() position = %v, want %v", decoded.position(), game.position())
+	}
+}
+
+func TestNotation_Teams(t *testing.T) {
+	rules := TeamRuleset()
+	rules.TeamTransfers = true
+	rules.NoMirror = true
+
+	game := NewGame("game", rules)
+	for _, id := range []string{"player 1", "player 2", "player 3", "player 4"} {
+		if err := game.AddPlayer(NewPlayer(id, "")); err != nil {
+			t.Fatalf("Game.AddPlayer() error = %v", err)
+		}
+	}
+	if err := game.StartGame(); err != nil {
+		t.Fatalf("Game.StartGame() error = %v", err)
+	}
+	if err := game.Apply(TransferMove(2, true, true, 1)); err != nil {
+		t.Fatalf("Game.Apply() error = %v", err)
+	}
+
+	var buf strings.Builder
+	if err := WriteNotation(&buf, game); err != nil {
+		t.Fatalf("WriteNotation() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), `[Teams "true"]`) || !strings.Contains(buf.String(), "1. L>3L1") {
+		t.Errorf("WriteNotation() = %q", buf.String())
+	}
+
+	decoded, err := ReadNotation(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatalf("ReadNotation() error = %v", err)
+	}
+	if decoded.Rules != rules || decoded.position() != game.position() {
+		t.Errorf("ReadNotation() rules = %v position = %v, want %v %v", decoded.Rules, decoded.position(), rules, game.position())
+	}
+}
+
+func TestNotation_RoundTripResults(t *testing.T) {
+	// play returns a game after tokens, ended by end if not nil
+	play := func(t *testing.T, tokens string, end func(*Game) error) *Game {
+		t.Helper()
+		game := newStartedGame(t, DefaultRuleset())
+		for _, token := range strings.Fields(tokens) {
+			m, err := game.ParseMove(token)
+			if err != nil {
+				t.Fatalf("Game.ParseMove(%s) error = %v", token, err)
+			}
+			if err := game.Apply(m); err != nil {
+				t.Fatalf("Game.Apply(%s) error = %v", token, err)
+			}
+		}
+		if end != nil {
+			if err := end(game); err != nil {
+				t.Fatalf("ending game error = %v", err)
+			}
+		}
+		return game
+	}
+
+	tests := []struct {
+		reason ResultReason
+		game   func(t *testing.T) *Game
+	}{
+		{ResultElimination, func(t *testing.T) *Game { return play(t, "LxL LxL LxL RxL LxR", nil) }},
+		{ResultRepetition, func(t *testing.T) *Game { return play(t, "L1 L1 R1 R1 L1 L1 R1 R1", nil) }},
+		{ResultMoveLimit, func(t *testing.T) *Game {
+			return play(t, "LxL", func(g *Game) error {
+				g.MaxMoves = 2
+				return g.Apply(AttackMove(0, true, true))
+			})
+		}},
+		{ResultTimeout, func(t *testing.T) *Game {
+			return play(t, "LxL", func(g *Game) error { g.Expire(); return nil })
+		}},
+		{ResultFlag, func(t *testing.T) *Game {
+			return play(t, "LxL", func(g *Game) error {
+				g.TimeControl = &TimeControl{Base: time.Second, Increment: 0, PerMove: 0}
+				g.startClocks(time.Now())
+				if !g.CheckFlag(time.Now().Add(time.Minute)) {
+					return fmt.Errorf("no flag fall")
+				}
+				return nil
+			})
+		}},
+		{ResultAbandoned, func(t *testing.T) *Game {
+			return play(t, "LxL LxL", func(g *Game) error { return g.Forfeit("player 2", ResultAbandoned) })
+		}},
+		{ResultResignation, func(t *testing.T) *Game {
+			return play(t, "LxL", func(g *Game) error { return g.Resign("player 1") })
+		}},
+		{ResultAgreement, func(t *testing.T) *Game {
+			return play(t, "LxL", func(g *Game) error {
+				return errors.Join(g.OfferDraw("player 1"), g.AcceptDraw("player 2"))
+			})
+		}},
+		{ResultAborted, func(t *testing.T) *Game {
+			return play(t, "", func(g *Game) error { return g.Abort("player 2") })
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.reason), func(t *testing.T) {
+			game := tt.game(t)
+			if game.Result != tt.reason {
+				t.Fatalf("game result = %s, want %s", game.Result, tt.reason)
+			}
+
+			var buf bytes.Buffer
+			if err := WriteNotation(&buf, game); err != nil {
+				t.Fatalf("WriteNotation() error = %v", err)
+			}
+			decoded, err := ReadNotation(strings.NewReader(buf.String()))
+			if err != nil {
+				t.Fatalf("ReadNotation() error = %v\n%s", err, buf.String())
+			}
+			if decoded.State != game.State || decoded.Result != game.Result || decoded.result() != game.result() {
+				t.Errorf("ReadNotation() state = %s result = %s (%s), want %s %s (%s)",
+					decoded.State, decoded.Result, decoded.result(), game.State, game.Result, game.result())
+			}
+			if decoded.position() != game.position() {
+				t.Errorf("ReadNotation() position = %v, want %v", decoded.position(), game.position())
+			}
+		})
+	}
+}
+
+func TestReadNotation_UnknownTermination(t *testing.T) {
+	text := `[Game "g"]
+[Result "1-0"]
+[Termination "boredom"]
+
+1. LxL 1-0
+`
+	if _, err := ReadNotation(strings.NewReader(text)); err == nil {
+		t.Errorf("ReadNotation() with an unknown termination should fail")
+	}
+}
@@ -5,6 +5,8 @@ type Player struct {
 	Name      string `json:"name"`
 	LeftHand  *Hand  `json:"leftHand"`
 	RightHand *Hand  `json:"rightHand"`
+	Team      int    `json:"team"`             // players on the same team may not attack each other
+	Rating    int    `json:"rating,omitempty"` // rating when the game started, 0 if unrated
 }
 
 func (p *Player) Alive() bool {
@@ -19,11 +21,32 @@ func (p *Player) GetHand(isLeft bool) *Hand {
 	return p.RightHand
 }
 
+// applyRuleset resets both hands to the ruleset's starting position
+func (p *Player) applyRuleset(rules Ruleset) {
+	for _, hand := range []*Hand{p.LeftHand, p.RightHand} {
+		hand.rules = rules
+		hand.fingers = rules.StartingFingers
+	}
+}
+
+// clone returns a copy of the player with their own hands
+func (p *Player) clone() *Player {
+	if p == nil {
+		return nil
+	}
+	c := *p
+	left, right := *p.LeftHand, *p.RightHand
+	c.LeftHand, c.RightHand = &left, &right
+	return &c
+}
+
 func NewPlayer(id, name string) *Player {
 	return &Player{
 		ID:        id,
 		Name:      name,
 		LeftHand:  NewHand(),
 		RightHand: NewHand(),
+		Team:      0,
+		Rating:    0,
 	}
 }
@@ -0,0 +1,136 @@
+package sticks
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Position is the state of a game: the fingers on every hand plus the player
+// to move. Positions are comparable, so they can be used as map keys; seats
+// past Players are always zero.
+type Position struct {
+	Hands   [MaxPlayers][2]uint8 `json:"hands"` // [seat][left, right]
+	Players uint8                `json:"players"`
+	Turn    uint8                `json:"turn"`
+}
+
+// Position returns the game's current position
+func (g *Game) Position() Position {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+	return g.position()
+}
+
+// position returns the game's current position without locking
+func (g *Game) position() Position {
+	p := Position{
+		Hands:   [MaxPlayers][2]uint8{},
+		Players: uint8(len(g.Players)),
+		Turn:    uint8(g.CurrentTurn),
+	}
+	for i, player := range g.Players {
+		p.Hands[i] = [2]uint8{uint8(player.LeftHand.fingers), uint8(player.RightHand.fingers)}
+	}
+	return p
+}
+
+// startingPosition returns the position every game under rules starts from
+func startingPosition(rules Ruleset) Position {
+	p := Position{
+		Hands:   [MaxPlayers][2]uint8{},
+		Players: uint8(rules.Players),
+		Turn:    0,
+	}
+	for i := range rules.Players {
+		p.Hands[i] = [2]uint8{uint8(rules.StartingFingers), uint8(rules.StartingFingers)}
+	}
+	return p
+}
+
+// Canonical returns the position with each player's hands in ascending order.
+// A player's left and right hands play the same, so positions that only differ
+// by swapping them share a canonical form.
+func (p Position) Canonical() Position {
+	for i := range p.Players {
+		if p.Hands[i][0] > p.Hands[i][1] {
+			p.Hands[i][0], p.Hands[i][1] = p.Hands[i][1], p.Hands[i][0]
+		}
+	}
+	return p
+}
+
+// Hash returns a 64 bit FNV-1a hash of the position. Hash the Canonical form
+// to treat mirrored hands as the same position.
+func (p Position) Hash() uint64 {
+	const (
+		offset = 14695981039346656037
+		prime  = 1099511628211
+	)
+	h := uint64(offset)
+	mix := func(b uint8) {
+		h ^= uint64(b)
+		h *= prime
+	}
+	mix(p.Players)
+	mix(p.Turn)
+	for _, hands := range p.Hands[:p.Players] {
+		mix(hands[0])
+		mix(hands[1])
+	}
+	return h
+}
+
+// Game builds an in-progress game under rules sitting at the position, with
+// placeholder players
+func (p Position) Game(rules Ruleset) (*Game, error) {
+	players := make([]*Player, p.Players)
+	for i := range players {
+		players[i] = NewPlayer(fmt.Sprintf("player%d", i+1), "")
+	}
+	return p.game(rules, players)
+}
+
+// game builds an in-progress game under rules sitting at the position, with
+// players seated in order
+func (p Position) game(rules Ruleset, players []*Player) (*Game, error) {
+	if int(p.Players) != rules.Players {
+		return nil, fmt.Errorf("position has %d players, rules need %d", p.Players, rules.Players)
+	}
+	if p.Turn >= p.Players {
+		return nil, fmt.Errorf("no player at seat %d", p.Turn)
+	}
+	for _, hands := range p.Hands[:p.Players] {
+		for _, f := range hands {
+			if int(f) >= rules.Fingers {
+				return nil, fmt.Errorf("hand with %d fingers is past the limit of %d", f, rules.Fingers)
+			}
+		}
+	}
+
+	g := NewGame("position", rules)
+	for _, player := range players {
+		if err := g.AddPlayer(player); err != nil {
+			return nil, err
+		}
+	}
+	if err := g.StartGame(); err != nil {
+		return nil, err
+	}
+	for i, player := range g.Players {
+		player.LeftHand.Set(int(p.Hands[i][0]))
+		player.RightHand.Set(int(p.Hands[i][1]))
+	}
+	g.CurrentTurn = int(p.Turn)
+	g.repetitions = map[Position]int{p.Canonical(): 1}
+	return g, nil
+}
+
+// String writes the position as each player's hands followed by the player to
+// move, as "1,1 vs 2,1, player 1 to move"
+func (p Position) String() string {
+	hands := make([]string, p.Players)
+	for i, h := range p.Hands[:p.Players] {
+		hands[i] = fmt.Sprintf("%d,%d", h[0], h[1])
+	}
+	return fmt.Sprintf("%s, player %d to move", strings.Join(hands, " vs "), p.Turn+1)
+}
@@ -0,0 +1,55 @@
+package sticks
+
+import (
+	"testing"
+)
+
+func TestPosition_Canonical(t *testing.T) {
+	a := Position{Hands: [MaxPlayers][2]uint8{{3, 1}, {0, 2}}, Players: 2, Turn: 1}
+	b := Position{Hands: [MaxPlayers][2]uint8{{1, 3}, {2, 0}}, Players: 2, Turn: 1}
+	other := Position{Hands: [MaxPlayers][2]uint8{{1, 3}, {2, 0}}, Players: 2, Turn: 0}
+
+	if a == b {
+		t.Fatalf("mirrored positions should differ before canonicalising")
+	}
+	if a.Canonical() != b.Canonical() {
+		t.Errorf("Position.Canonical() = %v and %v, want equal", a.Canonical(), b.Canonical())
+	}
+	if a.Canonical().Hash() != b.Canonical().Hash() {
+		t.Errorf("Position.Hash() differs for the same canonical position")
+	}
+	if b.Canonical().Hash() == other.Canonical().Hash() {
+		t.Errorf("Position.Hash() ignores the player to move")
+	}
+	if got, want := a.Canonical().String(), "1,3 vs 0,2, player 2 to move"; got != want {
+		t.Errorf("Position.String() = %q, want %q", got, want)
+	}
+}
+
+func TestPosition_Game(t *testing.T) {
+	game := newStartedGame(t, DefaultRuleset())
+	for _, m := range []Move{AttackMove(1, true, true), SplitMove(true, 1)} {
+		if err := game.Apply(m); err != nil {
+			t.Fatalf("Game.Apply(%v) error = %v", m, err)
+		}
+	}
+
+	p := game.Position()
+	rebuilt, err := p.Game(DefaultRuleset())
+	if err != nil {
+		t.Fatalf("Position.Game() error = %v", err)
+	}
+	if got := rebuilt.Position(); got != p {
+		t.Errorf("Position.Game().Position() = %v, want %v", got, p)
+	}
+
+	threePlayers := DefaultRuleset()
+	threePlayers.Players = 3
+	if _, err := p.Game(threePlayers); err == nil {
+		t.Errorf("Position.Game() with the wrong number of players should fail")
+	}
+	p.Hands[0][0] = 5
+	if _, err := p.Game(DefaultRuleset()); err == nil {
+		t.Errorf("Position.Game() with a hand past the limit should fail")
+	}
+}
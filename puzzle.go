@@ -0,0 +1,223 @@
+package sticks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Puzzle is a position where the player to move can force a win in exactly
+// Moves of their own moves, and only one first move does it
+type Puzzle struct {
+	ID       string   `json:"id"`
+	Position Position `json:"position"`
+	Moves    int      `json:"moves"`
+	Solution Move     `json:"solution"` // the winning first move
+}
+
+// PuzzleSet is a collection of puzzles for one ruleset, as stored on disk
+type PuzzleSet struct {
+	Version int      `json:"version"`
+	Rules   Ruleset  `json:"rules"`
+	Puzzles []Puzzle `json:"puzzles"`
+}
+
+const puzzleSetVersion = 1
+
+// GeneratePuzzles finds every win in 1 to maxMoves puzzle in a tablebase.
+// Positions that only differ by mirrored hands are included once.
+func GeneratePuzzles(tb *Tablebase, maxMoves int) (*PuzzleSet, error) {
+	if maxMoves < 1 {
+		return nil, fmt.Errorf("puzzles need at least 1 move, got %d", maxMoves)
+	}
+
+	set := &PuzzleSet{Version: puzzleSetVersion, Rules: tb.Rules, Puzzles: nil}
+	for moves := 1; moves <= maxMoves; moves++ {
+		for idx, entry := range tb.entries {
+			if entry.Outcome != OutcomeWin || entry.Distance != 2*moves-1 {
+				continue
+			}
+			p := positionAt(idx, 2, tb.Rules.Fingers)
+			if p != p.Canonical() {
+				continue
+			}
+
+			solution, unique, err := tb.uniqueWin(p)
+			if err != nil {
+				return nil, err
+			}
+			if !unique {
+				continue
+			}
+			set.Puzzles = append(set.Puzzles, Puzzle{
+				ID:       fmt.Sprintf("%016x", p.Hash()),
+				Position: p,
+				Moves:    moves,
+				Solution: solution,
+			})
+		}
+	}
+	return set, nil
+}
+
+// uniqueWin returns the winning move from a won position, and whether it is
+// the only one. Moves reaching the same canonical position count as one.
+func (tb *Tablebase) uniqueWin(p Position) (Move, bool, error) {
+	g, err := p.Game(tb.Rules)
+	if err != nil {
+		return Move{}, false, err
+	}
+
+	var solution Move
+	wins := map[Position]bool{}
+	for _, m := range g.legalMoves() {
+		child := g.clone()
+		if err := child.apply(m); err != nil {
+			return Move{}, false, err
+		}
+		q := child.position()
+		q.Turn = 1 - p.Turn
+		if entry, ok := tb.probe(q); ok && entry.Outcome == OutcomeLoss {
+			if len(wins) == 0 {
+				solution = m
+			}
+			wins[q.Canonical()] = true
+		}
+	}
+	return solution, len(wins) == 1, nil
+}
+
+// Find returns the puzzle with id
+func (s *PuzzleSet) Find(id string) (Puzzle, bool) {
+	for _, p := range s.Puzzles {
+		if p.ID == id {
+			return p, true
+		}
+	}
+	return Puzzle{}, false
+}
+
+// Daily returns the puzzle for the day containing t, cycling through the set
+func (s *PuzzleSet) Daily(t time.Time) (Puzzle, bool) {
+	if len(s.Puzzles) == 0 {
+		return Puzzle{}, false
+	}
+	day := t.UTC().Unix() / int64(24*time.Hour/time.Second)
+	return s.Puzzles[int(day%int64(len(s.Puzzles)))], true
+}
+
+// WriteTo saves the puzzle set as JSON
+func (s *PuzzleSet) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w, n: 0}
+	enc := json.NewEncoder(cw)
+	enc.SetIndent("", "  ")
+	err := enc.Encode(s)
+	return cw.n, err
+}
+
+// ReadPuzzleSet loads a puzzle set saved by WriteTo
+func ReadPuzzleSet(r io.Reader) (*PuzzleSet, error) {
+	var set PuzzleSet
+	if err := json.NewDecoder(r).Decode(&set); err != nil {
+		return nil, err
+	}
+	if set.Version != puzzleSetVersion {
+		return nil, fmt.Errorf("unsupported puzzle set version %d", set.Version)
+	}
+	if err := set.Rules.Validate(); err != nil {
+		return nil, err
+	}
+	for _, p := range set.Puzzles {
+		if p.Moves < 1 {
+			return nil, fmt.Errorf("puzzle %s needs at least 1 move", p.ID)
+		}
+		if _, err := p.Position.Game(set.Rules); err != nil {
+			return nil, fmt.Errorf("puzzle %s: %w", p.ID, err)
+		}
+	}
+	return &set, nil
+}
+
+// PuzzleStatus is how far a player has got with a puzzle
+type PuzzleStatus string
+
+const (
+	PuzzleStatusPlaying PuzzleStatus = "playing"
+	PuzzleStatusSolved  PuzzleStatus = "solved"
+	PuzzleStatusFailed  PuzzleStatus = "failed" // a move let the win slip or took too long
+)
+
+// PuzzleAttempt is a player working through a puzzle against a perfect
+// defender that holds out as long as it can
+type PuzzleAttempt struct {
+	Puzzle    Puzzle
+	Game      *Game
+	Status    PuzzleStatus
+	MovesLeft int // moves the player has left to win
+	tablebase *Tablebase
+	defender  Bot
+	seat      int // the player's seat
+}
+
+// NewPuzzleAttempt seats player at the puzzle's side to move. The tablebase
+// must be solved for the puzzle's rules.
+func NewPuzzleAttempt(puzzle Puzzle, tb *Tablebase, player *Player) (*PuzzleAttempt, error) {
+	seat := int(puzzle.Position.Turn)
+	players := make([]*Player, puzzle.Position.Players)
+	for i := range players {
+		players[i] = NewPlayer(fmt.Sprintf("defender_%d", i+1), "defender")
+	}
+	players[seat] = player
+
+	game, err := puzzle.Position.game(tb.Rules, players)
+	if err != nil {
+		return nil, err
+	}
+	game.ID = fmt.Sprintf("puzzle_%s_%d", puzzle.ID, time.Now().UnixNano())
+	if entry, ok := tb.Lookup(game); !ok || entry.Outcome != OutcomeWin || entry.Distance > 2*puzzle.Moves-1 {
+		return nil, fmt.Errorf("puzzle %s is not a win in %d for these rules", puzzle.ID, puzzle.Moves)
+	}
+
+	return &PuzzleAttempt{
+		Puzzle:    puzzle,
+		Game:      game,
+		Status:    PuzzleStatusPlaying,
+		MovesLeft: puzzle.Moves,
+		tablebase: tb,
+		defender:  NewPerfectBot(tb),
+		seat:      seat,
+	}, nil
+}
+
+// Play makes the player's move and, if the win is still on, the defender's
+// reply
+func (a *PuzzleAttempt) Play(m Move) error {
+	if a.Status != PuzzleStatusPlaying {
+		return fmt.Errorf("puzzle is already %s", a.Status)
+	}
+	if a.Game.GetTurn() != a.seat {
+		return fmt.Errorf("not your turn")
+	}
+	if err := a.Game.Apply(m); err != nil {
+		return err
+	}
+	a.MovesLeft--
+
+	if a.Game.IsOver() {
+		a.Status = PuzzleStatusSolved
+		return nil
+	}
+	// the defender must be lost in time for the player's remaining moves
+	entry, ok := a.tablebase.Lookup(a.Game)
+	if !ok || entry.Outcome != OutcomeLoss || entry.Distance/2 > a.MovesLeft {
+		a.Status = PuzzleStatusFailed
+		return nil
+	}
+
+	reply, err := a.defender.ChooseMove(a.Game)
+	if err != nil {
+		return err
+	}
+	return a.Game.Apply(reply)
+}
@@ -0,0 +1,139 @@
+package sticks
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGeneratePuzzles(t *testing.T) {
+	tb, err := Solve(DefaultRuleset())
+	if err != nil {
+		t.Fatalf("Solve() error = %v", err)
+	}
+	set, err := GeneratePuzzles(tb, 3)
+	if err != nil {
+		t.Fatalf("GeneratePuzzles() error = %v", err)
+	}
+	if len(set.Puzzles) == 0 {
+		t.Fatalf("GeneratePuzzles() found no puzzles")
+	}
+
+	ids := map[string]bool{}
+	for _, p := range set.Puzzles {
+		if ids[p.ID] {
+			t.Errorf("puzzle id %s is not unique", p.ID)
+		}
+		ids[p.ID] = true
+
+		game, err := p.Position.Game(set.Rules)
+		if err != nil {
+			t.Fatalf("Position.Game() error = %v", err)
+		}
+		if entry, _ := tb.Lookup(game); entry.Outcome != OutcomeWin || entry.Distance != 2*p.Moves-1 {
+			t.Errorf("puzzle %v = %v, want a win in %d", p.Position, entry, p.Moves)
+		}
+		if err := game.Apply(p.Solution); err != nil {
+			t.Fatalf("Game.Apply(%v) error = %v", p.Solution, err)
+		}
+		if entry, _ := tb.Lookup(game); !game.IsOver() && entry.Outcome != OutcomeLoss {
+			t.Errorf("puzzle %v solution %v leaves the opponent %v", p.Position, p.Solution, entry)
+		}
+	}
+
+	if _, err := GeneratePuzzles(tb, 0); err == nil {
+		t.Errorf("GeneratePuzzles() with no moves should fail")
+	}
+}
+
+func TestPuzzleSet_RoundTrip(t *testing.T) {
+	tb, err := Solve(DefaultRuleset())
+	if err != nil {
+		t.Fatalf("Solve() error = %v", err)
+	}
+	set, err := GeneratePuzzles(tb, 2)
+	if err != nil {
+		t.Fatalf("GeneratePuzzles() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := set.WriteTo(&buf); err != nil {
+		t.Fatalf("PuzzleSet.WriteTo() error = %v", err)
+	}
+	loaded, err := ReadPuzzleSet(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("ReadPuzzleSet() error = %v", err)
+	}
+	if !reflect.DeepEqual(loaded, set) {
+		t.Errorf("ReadPuzzleSet() did not round trip")
+	}
+
+	daily, ok := loaded.Daily(time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC))
+	if again, _ := loaded.Daily(time.Date(2025, 6, 1, 23, 0, 0, 0, time.UTC)); !ok || again != daily {
+		t.Errorf("PuzzleSet.Daily() changed within a day")
+	}
+	if found, ok := loaded.Find(daily.ID); !ok || found != daily {
+		t.Errorf("PuzzleSet.Find(%s) = %v, %v", daily.ID, found, ok)
+	}
+
+	if _, err := ReadPuzzleSet(strings.NewReader(`{"version": 2}`)); err == nil {
+		t.Errorf("ReadPuzzleSet() with an unknown version should fail")
+	}
+}
+
+func TestPuzzleAttempt(t *testing.T) {
+	tb, err := Solve(DefaultRuleset())
+	if err != nil {
+		t.Fatalf("Solve() error = %v", err)
+	}
+	set, err := GeneratePuzzles(tb, 3)
+	if err != nil {
+		t.Fatalf("GeneratePuzzles() error = %v", err)
+	}
+	puzzle := set.Puzzles[len(set.Puzzles)-1]
+	if puzzle.Moves != 3 {
+		t.Fatalf("no win in 3 puzzle to test with")
+	}
+	analyzer := NewAnalyzer(tb)
+
+	t.Run("solved", func(t *testing.T) {
+		attempt, err := NewPuzzleAttempt(puzzle, tb, NewPlayer("solver", ""))
+		if err != nil {
+			t.Fatalf("NewPuzzleAttempt() error = %v", err)
+		}
+		for attempt.Status == PuzzleStatusPlaying {
+			analyses, err := analyzer.Analyze(attempt.Game)
+			if err != nil {
+				t.Fatalf("Analyzer.Analyze() error = %v", err)
+			}
+			if err := attempt.Play(analyses[0].Move); err != nil {
+				t.Fatalf("PuzzleAttempt.Play() error = %v", err)
+			}
+		}
+		if attempt.Status != PuzzleStatusSolved || attempt.MovesLeft < 0 {
+			t.Errorf("PuzzleAttempt status = %s with %d moves left, want solved", attempt.Status, attempt.MovesLeft)
+		}
+		if err := attempt.Play(puzzle.Solution); err == nil {
+			t.Errorf("PuzzleAttempt.Play() after the puzzle ended should fail")
+		}
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		attempt, err := NewPuzzleAttempt(puzzle, tb, NewPlayer("solver", ""))
+		if err != nil {
+			t.Fatalf("NewPuzzleAttempt() error = %v", err)
+		}
+		analyses, err := analyzer.Analyze(attempt.Game)
+		if err != nil {
+			t.Fatalf("Analyzer.Analyze() error = %v", err)
+		}
+		if err := attempt.Play(analyses[len(analyses)-1].Move); err != nil {
+			t.Fatalf("PuzzleAttempt.Play() error = %v", err)
+		}
+		if attempt.Status != PuzzleStatusFailed {
+			t.Errorf("PuzzleAttempt status = %s, want failed", attempt.Status)
+		}
+	})
+}
@@ -0,0 +1,231 @@
+package sticks
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Glicko-2 constants. Ratings are kept on the familiar Glicko scale and
+// converted to the Glicko-2 scale for updates.
+const (
+	defaultRating     = 1500.0
+	defaultDeviation  = 350.0
+	defaultVolatility = 0.06
+	// glickoScale converts between the Glicko and Glicko-2 scales
+	glickoScale = 173.7178
+	// ratingTau limits how fast volatility changes
+	ratingTau = 0.5
+	// volatilityEpsilon is when the volatility search stops
+	volatilityEpsilon = 0.000001
+)
+
+// Rating is a player's Glicko-2 skill rating
+type Rating struct {
+	Value      float64 `json:"rating"`
+	Deviation  float64 `json:"deviation"`  // uncertainty in Value, shrinking with every game
+	Volatility float64 `json:"volatility"` // how erratic the player's results are
+}
+
+// NewRating returns the rating of a player who has not played yet
+func NewRating() Rating {
+	return Rating{Value: defaultRating, Deviation: defaultDeviation, Volatility: defaultVolatility}
+}
+
+// RatingResult is one result to rate a player on: Score is 1 for a win, 0.5
+// for a draw and 0 for a loss against Opponent
+type RatingResult struct {
+	Opponent Rating
+	Score    float64
+}
+
+// Update returns the rating after one rating period with results. With no
+// results only the deviation grows.
+func (r Rating) Update(results []RatingResult) Rating {
+	mu := (r.Value - defaultRating) / glickoScale
+	phi := r.Deviation / glickoScale
+	if len(results) == 0 {
+		r.Deviation = math.Sqrt(phi*phi+r.Volatility*r.Volatility) * glickoScale
+		return r
+	}
+
+	// estimated variance and improvement from the results
+	var variance, improvement float64
+	for _, o := range results {
+		muJ := (o.Opponent.Value - defaultRating) / glickoScale
+		g := glickoG(o.Opponent.Deviation / glickoScale)
+		e := 1 / (1 + math.Exp(-g*(mu-muJ)))
+		variance += g * g * e * (1 - e)
+		improvement += g * (o.Score - e)
+	}
+	variance = 1 / variance
+	delta := variance * improvement
+
+	sigma := newVolatility(phi, r.Volatility, variance, delta)
+	phiStar := math.Sqrt(phi*phi + sigma*sigma)
+	phi = 1 / math.Sqrt(1/(phiStar*phiStar)+1/variance)
+	mu += phi * phi * improvement
+
+	return Rating{
+		Value:      mu*glickoScale + defaultRating,
+		Deviation:  phi * glickoScale,
+		Volatility: sigma,
+	}
+}
+
+// glickoG weighs a result by how certain the opponent's rating is
+func glickoG(phi float64) float64 {
+	return 1 / math.Sqrt(1+3*phi*phi/(math.Pi*math.Pi))
+}
+
+// newVolatility finds the new volatility with the Illinois algorithm, as
+// laid out in step 5 of Glickman's description of Glicko-2
+func newVolatility(phi, sigma, variance, delta float64) float64 {
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		d := phi*phi + variance + ex
+		return ex*(delta*delta-phi*phi-variance-ex)/(2*d*d) - (x-a)/(ratingTau*ratingTau)
+	}
+
+	lower := a
+	var upper float64
+	if delta*delta > phi*phi+variance {
+		upper = math.Log(delta*delta - phi*phi - variance)
+	} else {
+		k := 1.0
+		for f(a-k*ratingTau) < 0 {
+			k++
+		}
+		upper = a - k*ratingTau
+	}
+
+	fLower, fUpper := f(lower), f(upper)
+	for math.Abs(upper-lower) > volatilityEpsilon {
+		c := lower + (lower-upper)*fLower/(fUpper-fLower)
+		fC := f(c)
+		if fC*fUpper <= 0 {
+			lower, fLower = upper, fUpper
+		} else {
+			fLower /= 2
+		}
+		upper, fUpper = c, fC
+	}
+	return math.Exp(lower / 2)
+}
+
+// PlayerRating is a player's rating with their rated record
+type PlayerRating struct {
+	PlayerID string `json:"playerId"`
+	Rating
+	Games     int       `json:"games"`
+	Wins      int       `json:"wins"`
+	Losses    int       `json:"losses"`
+	Draws     int       `json:"draws"`
+	UpdatedAt time.Time `json:"updatedAt,omitzero"` // zero until the first rated game
+}
+
+// Ratings keeps every player's rating, updated as rated games finish
+type Ratings struct {
+	mutex   *sync.RWMutex
+	players map[string]PlayerRating
+}
+
+// NewRatings returns an empty set of ratings
+func NewRatings() *Ratings {
+	return &Ratings{
+		mutex:   &sync.RWMutex{},
+		players: make(map[string]PlayerRating),
+	}
+}
+
+// Get returns a player's rating, the starting rating if they have none yet
+func (r *Ratings) Get(playerID string) PlayerRating {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.get(playerID)
+}
+
+// get returns a player's rating without locking
+func (r *Ratings) get(playerID string) PlayerRating {
+	if rating, ok := r.players[playerID]; ok {
+		return rating
+	}
+	return PlayerRating{
+		PlayerID:  playerID,
+		Rating:    NewRating(),
+		Games:     0,
+		Wins:      0,
+		Losses:    0,
+		Draws:     0,
+		UpdatedAt: time.Time{},
+	}
+}
+
+// Record rates the players of a finished game, reporting whether the game
+// counted. Practice games, aborted games and games that ended without a
+// result are not rated.
+//
+// Every player is rated against each opponent on another team, all from
+// their ratings before the game. In a free for all the winner beats every
+// other player, and the players knocked out are not rated against each other.
+func (r *Ratings) Record(game *Game) bool {
+	if game.Practice {
+		return false
+	}
+	snapshot := game.Snapshot()
+	winningTeam := -1
+	switch snapshot.State {
+	case GameStateFinished:
+		for _, p := range snapshot.Players {
+			if p.ID == snapshot.Winner {
+				winningTeam = p.Team
+			}
+		}
+		if winningTeam < 0 {
+			return false
+		}
+	case GameStateDraw:
+	default:
+		return false
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	before := make([]PlayerRating, len(snapshot.Players))
+	for i, p := range snapshot.Players {
+		before[i] = r.get(p.ID)
+	}
+	for i, p := range snapshot.Players {
+		var results []RatingResult
+		for j, opponent := range snapshot.Players {
+			if opponent.Team == p.Team {
+				continue
+			}
+			switch {
+			case winningTeam < 0:
+				results = append(results, RatingResult{Opponent: before[j].Rating, Score: 0.5})
+			case p.Team == winningTeam:
+				results = append(results, RatingResult{Opponent: before[j].Rating, Score: 1})
+			case opponent.Team == winningTeam:
+				results = append(results, RatingResult{Opponent: before[j].Rating, Score: 0})
+			}
+		}
+
+		rating := before[i]
+		rating.Rating = rating.Rating.Update(results)
+		rating.Games++
+		switch {
+		case winningTeam < 0:
+			rating.Draws++
+		case p.Team == winningTeam:
+			rating.Wins++
+		default:
+			rating.Losses++
+		}
+		rating.UpdatedAt = snapshot.TakenAt
+		r.players[p.ID] = rating
+	}
+	return true
+}
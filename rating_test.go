@@ -0,0 +1,98 @@
+package sticks
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestRating_Update(t *testing.T) {
+	// the worked example from Glickman's description of Glicko-2
+	player := Rating{Value: 1500, Deviation: 200, Volatility: 0.06}
+	got := player.Update([]RatingResult{
+		{Opponent: Rating{Value: 1400, Deviation: 30, Volatility: 0.06}, Score: 1},
+		{Opponent: Rating{Value: 1550, Deviation: 100, Volatility: 0.06}, Score: 0},
+		{Opponent: Rating{Value: 1700, Deviation: 300, Volatility: 0.06}, Score: 0},
+	})
+	want := Rating{Value: 1464.06, Deviation: 151.52, Volatility: 0.05999}
+	if math.Abs(got.Value-want.Value) > 0.01 ||
+		math.Abs(got.Deviation-want.Deviation) > 0.01 ||
+		math.Abs(got.Volatility-want.Volatility) > 0.00001 {
+		t.Errorf("Rating.Update() = %+v, want %+v", got, want)
+	}
+
+	idle := player.Update(nil)
+	if idle.Value != player.Value || idle.Deviation <= player.Deviation {
+		t.Errorf("Rating.Update() without games = %+v, want only the deviation to grow", idle)
+	}
+}
+
+func TestRatings_Record(t *testing.T) {
+	ratings := NewRatings()
+
+	game := newStartedGame(t, DefaultRuleset())
+	if ratings.Record(game) {
+		t.Errorf("Ratings.Record() rated a game in progress")
+	}
+	if err := game.Resign("player 2"); err != nil {
+		t.Fatalf("Game.Resign() error = %v", err)
+	}
+	if !ratings.Record(game) {
+		t.Fatalf("Ratings.Record() did not rate a finished game")
+	}
+	winner, loser := ratings.Get("player 1"), ratings.Get("player 2")
+	if winner.Value <= defaultRating || loser.Value >= defaultRating {
+		t.Errorf("ratings after a win = %.0f and %.0f, want the winner above %.0f and the loser below", winner.Value, loser.Value, defaultRating)
+	}
+	if winner.Wins != 1 || loser.Losses != 1 || winner.Games != 1 {
+		t.Errorf("records = %+v and %+v, want one win and one loss", winner, loser)
+	}
+
+	drawn := newStartedGame(t, DefaultRuleset())
+	if err := errors.Join(drawn.OfferDraw("player 1"), drawn.AcceptDraw("player 2")); err != nil {
+		t.Fatalf("agreeing a draw: %v", err)
+	}
+	if !ratings.Record(drawn) {
+		t.Fatalf("Ratings.Record() did not rate a drawn game")
+	}
+	if got := ratings.Get("player 2"); got.Draws != 1 || got.Value <= loser.Value {
+		t.Errorf("lower rated player after a draw = %+v, want a gain from %.0f", got, loser.Value)
+	}
+
+	aborted := newStartedGame(t, DefaultRuleset())
+	if err := aborted.Abort("player 1"); err != nil {
+		t.Fatalf("Game.Abort() error = %v", err)
+	}
+	if ratings.Record(aborted) {
+		t.Errorf("Ratings.Record() rated an aborted game")
+	}
+	practice := newStartedGame(t, DefaultRuleset())
+	practice.Practice = true
+	if err := practice.Resign("player 1"); err != nil {
+		t.Fatalf("Game.Resign() error = %v", err)
+	}
+	if ratings.Record(practice) {
+		t.Errorf("Ratings.Record() rated a practice game")
+	}
+	if got := ratings.Get("player 1").Games; got != 2 {
+		t.Errorf("player 1 has %d rated games, want 2", got)
+	}
+}
+
+func TestRatings_RecordTeams(t *testing.T) {
+	ratings := NewRatings()
+	game := newStartedGame(t, TeamRuleset())
+	if err := errors.Join(game.Resign("player 2"), game.Resign("player 4")); err != nil {
+		t.Fatalf("Game.Resign() error = %v", err)
+	}
+	if !ratings.Record(game) {
+		t.Fatalf("Ratings.Record() did not rate a team game")
+	}
+	for i, wantWin := range []bool{true, false, true, false} {
+		r := ratings.Get(fmt.Sprintf("player %d", i+1))
+		if (r.Wins == 1) != wantWin || r.Games != 1 {
+			t.Errorf("player %d record = %+v, want win %v", i+1, r, wantWin)
+		}
+	}
+}
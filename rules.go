@@ -0,0 +1,163 @@
+package sticks
+
+import (
+	"errors"
+	"fmt"
+)
+
+// RuleVariant selects what happens to a hand that reaches the finger limit
+type RuleVariant string
+
+const (
+	// RuleVariantCutoff kills a hand once it reaches the finger limit
+	RuleVariantCutoff RuleVariant = "cutoff"
+	// RuleVariantRollover wraps a hand modulo the finger limit, so a hand only
+	// dies when it lands exactly on zero
+	RuleVariantRollover RuleVariant = "rollover"
+)
+
+// Ruleset configures the variant of chopsticks a game is played under
+type Ruleset struct {
+	Variant         RuleVariant `json:"variant"`
+	Fingers         int         `json:"fingers"`         // finger limit per hand
+	StartingFingers int         `json:"startingFingers"` // fingers on each hand when the game starts
+	Players         int         `json:"players"`         // seats at the table
+	Teams           bool        `json:"teams"`           // two teams of two, seated alternately
+	TeamTransfers   bool        `json:"teamTransfers"`   // teammates may give each other fingers
+	NoRevival       bool        `json:"noRevival"`       // points may not be moved into a dead hand
+	NoMirror        bool        `json:"noMirror"`        // splits may not just swap the two hands (2/3 to 3/2)
+	EvenSplitsOnly  bool        `json:"evenSplitsOnly"`  // splits must leave both hands equal
+	SplitOverflow   bool        `json:"splitOverflow"`   // points may push a hand past the limit, as an attack would
+}
+
+const (
+	MinPlayers = 2
+	MaxPlayers = 6
+	// MaxFingers is the highest finger limit, so a hand always fits in a byte
+	MaxFingers = 255
+	// TeamPlayers is the number of seats in a team game
+	TeamPlayers = 4
+)
+
+// DefaultRuleset returns the classic cutoff rules with five fingers per hand
+func DefaultRuleset() Ruleset {
+	return Ruleset{
+		Variant:         RuleVariantCutoff,
+		Fingers:         5,
+		StartingFingers: 1,
+		Players:         2,
+		Teams:           false,
+		TeamTransfers:   false,
+		NoRevival:       false,
+		NoMirror:        false,
+		EvenSplitsOnly:  false,
+		SplitOverflow:   false,
+	}
+}
+
+// TeamRuleset returns the default rules for two teams of two
+func TeamRuleset() Ruleset {
+	rules := DefaultRuleset()
+	rules.Players = TeamPlayers
+	rules.Teams = true
+	return rules
+}
+
+// Validate checks that the ruleset describes a playable game
+func (r Ruleset) Validate() error {
+	switch r.Variant {
+	case RuleVariantCutoff, RuleVariantRollover:
+	default:
+		return fmt.Errorf("unknown rule variant: %q", r.Variant)
+	}
+	if r.Fingers < 2 || r.Fingers > MaxFingers {
+		return fmt.Errorf("hands need between 2 and %d fingers, got %d", MaxFingers, r.Fingers)
+	}
+	if r.StartingFingers < 1 || r.StartingFingers >= r.Fingers {
+		return fmt.Errorf("starting fingers must be between 1 and %d, got %d", r.Fingers-1, r.StartingFingers)
+	}
+	if r.Players < MinPlayers || r.Players > MaxPlayers {
+		return fmt.Errorf("games need between %d and %d players, got %d", MinPlayers, MaxPlayers, r.Players)
+	}
+	if r.Teams && r.Players != TeamPlayers {
+		return fmt.Errorf("team games need %d players, got %d", TeamPlayers, r.Players)
+	}
+	if r.TeamTransfers && !r.Teams {
+		return fmt.Errorf("team transfers need team games")
+	}
+	return nil
+}
+
+// alive reports whether a hand holding fingers is still in play
+func (r Ruleset) alive(fingers int) bool {
+	return fingers > 0 && fingers < r.Fingers
+}
+
+// normalize applies the variant to a raw finger count
+func (r Ruleset) normalize(fingers int) int {
+	if r.Variant == RuleVariantRollover {
+		return fingers % r.Fingers
+	}
+	if fingers >= r.Fingers {
+		return 0
+	}
+	return fingers
+}
+
+// attack returns the defender's finger count after being hit by the attacker
+func (r Ruleset) attack(attacker, defender int) (int, error) {
+	if !r.alive(attacker) {
+		return 0, errors.New("attacking hand is dead")
+	}
+	if !r.alive(defender) {
+		return 0, errors.New("opponent hand is dead")
+	}
+	return r.normalize(defender + attacker), nil
+}
+
+// team returns the team of the player at seat. Without teams every player is
+// on a team of their own.
+func (r Ruleset) team(seat int) int {
+	if r.Teams {
+		return seat % 2
+	}
+	return seat
+}
+
+// transfer returns the finger counts of both hands after moving points from
+// one hand to the other
+func (r Ruleset) transfer(from, to, points int) (int, int, error) {
+	if points < 1 {
+		return 0, 0, errors.New("a split must move at least one point")
+	}
+	// check if this hand can take that many points without dying
+	if to+points > r.Fingers && !r.SplitOverflow {
+		return 0, 0, errors.New("this is more points than this hand can take")
+	}
+	if from-points < 0 {
+		return 0, 0, errors.New("the other hand does not have enough points")
+	}
+	if r.NoRevival && !r.alive(to) {
+		return 0, 0, errors.New("a dead hand cannot be revived")
+	}
+	return from - points, r.normalize(to + points), nil
+}
+
+// split returns the finger counts of a player's hands after moving points from
+// one to the other, applying the split restrictions on top of transfer
+func (r Ruleset) split(from, to, points int) (int, int, error) {
+	newFrom, newTo, err := r.transfer(from, to, points)
+	if err != nil {
+		return 0, 0, err
+	}
+	if r.NoMirror && newFrom == to && newTo == from {
+		return 0, 0, errors.New("a split cannot just swap the hands")
+	}
+	if r.EvenSplitsOnly && newFrom != newTo {
+		return 0, 0, errors.New("a split must leave both hands equal")
+	}
+	if !r.alive(newFrom) && !r.alive(newTo) {
+		return 0, 0, errors.New("a split cannot kill both hands")
+	}
+	return newFrom, newTo, nil
+}
@@ -0,0 +1,171 @@
+package sticks
+
+import (
+	"testing"
+)
+
+func TestRuleset_Attack(t *testing.T) {
+	rollover := DefaultRuleset()
+	rollover.Variant = RuleVariantRollover
+
+	sixFingers := DefaultRuleset()
+	sixFingers.Fingers = 6
+
+	tests := []struct {
+		name     string
+		rules    Ruleset
+		attacker int
+		defender int
+		want     int
+		wantErr  bool
+	}{
+		{
+			name:     "cutoff below limit",
+			rules:    DefaultRuleset(),
+			attacker: 2,
+			defender: 2,
+			want:     4,
+		},
+		{
+			name:     "cutoff reaching limit kills",
+			rules:    DefaultRuleset(),
+			attacker: 3,
+			defender: 4,
+			want:     0,
+		},
+		{
+			name:     "rollover wraps past limit",
+			rules:    rollover,
+			attacker: 3,
+			defender: 4,
+			want:     2,
+		},
+		{
+			name:     "rollover landing on limit kills",
+			rules:    rollover,
+			attacker: 2,
+			defender: 3,
+			want:     0,
+		},
+		{
+			name:     "six fingers survives five",
+			rules:    sixFingers,
+			attacker: 2,
+			defender: 3,
+			want:     5,
+		},
+		{
+			name:     "dead defender",
+			rules:    DefaultRuleset(),
+			attacker: 1,
+			defender: 0,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.rules.attack(tt.attacker, tt.defender)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Ruleset.attack() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Ruleset.attack() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleset_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   Ruleset
+		wantErr bool
+	}{
+		{
+			name:    "default",
+			rules:   DefaultRuleset(),
+			wantErr: false,
+		},
+		{
+			name:    "zero value",
+			rules:   Ruleset{Variant: "", Fingers: 0, StartingFingers: 0},
+			wantErr: true,
+		},
+		{
+			name:    "starting fingers at limit",
+			rules:   Ruleset{Variant: RuleVariantRollover, Fingers: 5, StartingFingers: 5},
+			wantErr: true,
+		},
+		{
+			name:    "teams",
+			rules:   TeamRuleset(),
+			wantErr: false,
+		},
+		{
+			name:    "teams with three players",
+			rules:   Ruleset{Variant: RuleVariantCutoff, Fingers: 5, StartingFingers: 1, Players: 3, Teams: true, TeamTransfers: false},
+			wantErr: true,
+		},
+		{
+			name:    "transfers without teams",
+			rules:   Ruleset{Variant: RuleVariantCutoff, Fingers: 5, StartingFingers: 1, Players: 2, Teams: false, TeamTransfers: true},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.rules.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Ruleset.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRuleset_Split(t *testing.T) {
+	rule := func(set func(r *Ruleset)) Ruleset {
+		r := DefaultRuleset()
+		set(&r)
+		return r
+	}
+	noRevival := rule(func(r *Ruleset) { r.NoRevival = true })
+	noMirror := rule(func(r *Ruleset) { r.NoMirror = true })
+	even := rule(func(r *Ruleset) { r.EvenSplitsOnly = true })
+	overflow := rule(func(r *Ruleset) { r.SplitOverflow = true })
+	rolloverOverflow := rule(func(r *Ruleset) { r.SplitOverflow = true; r.Variant = RuleVariantRollover })
+
+	tests := []struct {
+		name     string
+		rules    Ruleset
+		from     int
+		to       int
+		points   int
+		wantFrom int
+		wantTo   int
+		wantErr  bool
+	}{
+		{name: "default revives", rules: DefaultRuleset(), from: 4, to: 0, points: 2, wantFrom: 2, wantTo: 2},
+		{name: "default mirrors", rules: DefaultRuleset(), from: 3, to: 2, points: 1, wantFrom: 2, wantTo: 3},
+		{name: "default stops at limit", rules: DefaultRuleset(), from: 4, to: 3, points: 3, wantErr: true},
+		{name: "no revival", rules: noRevival, from: 4, to: 0, points: 2, wantErr: true},
+		{name: "no revival between living hands", rules: noRevival, from: 3, to: 1, points: 1, wantFrom: 2, wantTo: 2},
+		{name: "no mirror", rules: noMirror, from: 3, to: 2, points: 1, wantErr: true},
+		{name: "no mirror uneven", rules: noMirror, from: 3, to: 1, points: 1, wantFrom: 2, wantTo: 2},
+		{name: "even only", rules: even, from: 4, to: 0, points: 2, wantFrom: 2, wantTo: 2},
+		{name: "even only uneven", rules: even, from: 4, to: 0, points: 1, wantErr: true},
+		{name: "overflow kills", rules: overflow, from: 3, to: 3, points: 2, wantFrom: 1, wantTo: 0},
+		{name: "overflow wraps", rules: rolloverOverflow, from: 4, to: 3, points: 3, wantFrom: 1, wantTo: 1},
+		{name: "overflow cannot kill both", rules: overflow, from: 2, to: 3, points: 2, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, err := tt.rules.split(tt.from, tt.to, tt.points)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Ruleset.split() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (from != tt.wantFrom || to != tt.wantTo) {
+				t.Errorf("Ruleset.split() = %d, %d, want %d, %d", from, to, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
@@ -0,0 +1,517 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"log"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	gwebsocket "github.com/gorilla/websocket"
+	"github.com/tkahng/sticks"
+	"github.com/tkahng/sticks/websocket"
+)
+
+const (
+	// pingInterval is how often idle connections are pinged
+	pingInterval = 30 * time.Second
+	// pongWait is how long a connection may go without answering a ping
+	// before it counts as dropped
+	pongWait = 2 * pingInterval
+)
+
+// connection is a player's WebSocket. Every write goes through client, whose
+// writer goroutine runs until ctx is cancelled. A reader goroutine keeps
+// reading the whole time, so a dropped connection is noticed even while
+// nothing is waiting for a message.
+type connection struct {
+	client   websocket.Client
+	player   *sticks.Player
+	ctx      context.Context
+	cancel   context.CancelFunc
+	written  chan struct{} // closed once the writer has stopped
+	received chan received // closed after the connection error is delivered
+}
+
+// received is a message read from a connection, or the error reading it
+type received struct {
+	msg Message
+	err error
+}
+
+// newConnection starts the writer and reader for conn. Cancelling the
+// connection also stops its reader.
+func newConnection(conn *gwebsocket.Conn, player *sticks.Player) *connection {
+	client := websocket.NewClient(conn)
+	_ = client.SetLogger(slog.Default())
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &connection{
+		client:   client,
+		player:   player,
+		ctx:      ctx,
+		cancel:   cancel,
+		written:  make(chan struct{}),
+		received: make(chan received),
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+	go client.WriteForever(ctx, func(websocket.Client) { close(c.written) }, pingInterval)
+	go c.readForever()
+	go func() {
+		<-ctx.Done()
+		_ = conn.SetReadDeadline(time.Now())
+	}()
+	return c
+}
+
+// readForever reads messages until the connection fails. Messages read after
+// the connection is cancelled are dropped.
+func (c *connection) readForever() {
+	defer close(c.received)
+	for {
+		msg, err := readMessage(c.client.Conn())
+		select {
+		case c.received <- received{msg: msg, err: err}:
+		case <-c.ctx.Done():
+		}
+		var perr *Error
+		if err != nil && !errors.As(err, &perr) {
+			return
+		}
+	}
+}
+
+// read returns the next message from the player
+func (c *connection) read() (Message, error) {
+	r, ok := <-c.received
+	if !ok {
+		return Message{}, net.ErrClosed
+	}
+	return r.msg, r.err
+}
+
+// flush stops the connection once everything queued has been written
+func (c *connection) flush() {
+	c.cancel()
+	<-c.written
+}
+
+// close flushes and closes the connection
+func (c *connection) close() {
+	c.flush()
+	// nolint:errcheck
+	c.client.Close()
+}
+
+// gameHub tracks the connections to one game and pushes every change to all
+// of them, so players see each other's moves as they happen. A player whose
+// connection drops keeps their seat for the grace period, then forfeits.
+type gameHub struct {
+	game        *sticks.Game
+	broadcaster websocket.Broadcaster
+	stop        context.CancelFunc // stops the broadcaster
+	release     func(*gameHub)     // called when an abandoned game leaves the hub empty
+	grace       time.Duration
+	mutex       *sync.Mutex // serializes actions and guards the fields below
+	sending     *sync.Mutex // keeps updates in order while they are sent
+	connections map[websocket.Client]*connection
+	tokens      map[string]*sticks.Player // resume tokens issued for the game
+	absent      map[string]*time.Timer    // forfeit timers, by player ID
+	finished    bool                      // the result has been announced
+	outbox      []func()                  // updates to send once the hub is unlocked
+}
+
+// newGameHub creates a hub for game. session, if not nil, is the broker's
+// session for the game, which may flag players, end the game on time or
+// move for its bot.
+func newGameHub(game *sticks.Game, grace time.Duration, release func(*gameHub), session *sticks.GameSession) *gameHub {
+	ctx, stop := context.WithCancel(context.Background())
+	broadcaster := websocket.NewBroadcaster()
+	go broadcaster.Run(ctx)
+	h := &gameHub{
+		game:        game,
+		broadcaster: broadcaster,
+		stop:        stop,
+		release:     release,
+		grace:       grace,
+		mutex:       &sync.Mutex{},
+		sending:     &sync.Mutex{},
+		connections: make(map[websocket.Client]*connection),
+		tokens:      make(map[string]*sticks.Player),
+		absent:      make(map[string]*time.Timer),
+		finished:    false,
+		outbox:      nil,
+	}
+	if session != nil {
+		go h.watch(ctx, session)
+	}
+	return h
+}
+
+// hub returns the hub for a game, creating it for the first connection
+func (gs *GameServer) hub(game *sticks.Game) *gameHub {
+	gs.hubsMutex.Lock()
+	defer gs.hubsMutex.Unlock()
+
+	hub, ok := gs.hubs[game.ID]
+	if !ok {
+		session, _ := gs.broker.GetGameSession(game.ID)
+		hub = newGameHub(game, gs.reconnectGrace, gs.dropHub, session)
+		gs.hubs[game.ID] = hub
+	}
+	return hub
+}
+
+// issueToken returns a token that lets player back into the hub's game
+func (gs *GameServer) issueToken(hub *gameHub, player *sticks.Player) string {
+	token := rand.Text()
+
+	gs.hubsMutex.Lock()
+	gs.tokens[token] = hub
+	gs.hubsMutex.Unlock()
+
+	hub.mutex.Lock()
+	hub.tokens[token] = player
+	hub.mutex.Unlock()
+	return token
+}
+
+// resume finds the hub and player a resume token was issued for
+func (gs *GameServer) resume(token string) (*gameHub, *sticks.Player, error) {
+	gs.hubsMutex.Lock()
+	hub, ok := gs.tokens[token]
+	gs.hubsMutex.Unlock()
+	if !ok {
+		return nil, nil, newError(ErrorCodeNotFound, "unknown or expired resume token")
+	}
+
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+	if hub.game.IsOver() {
+		return nil, nil, newError(ErrorCodeGameOver, "game is over")
+	}
+	return hub, hub.tokens[token], nil
+}
+
+// leaveHub removes a connection from its hub, dropping the hub once nobody is
+// connected or expected back
+func (gs *GameServer) leaveHub(hub *gameHub, c *connection) {
+	hub.leave(c)
+	gs.dropHub(hub)
+}
+
+// dropHub forgets a hub and its resume tokens if it is empty
+func (gs *GameServer) dropHub(hub *gameHub) {
+	gs.hubsMutex.Lock()
+	defer gs.hubsMutex.Unlock()
+	if !hub.empty() || gs.hubs[hub.game.ID] != hub {
+		return
+	}
+
+	hub.stop()
+	delete(gs.hubs, hub.game.ID)
+	for token, h := range gs.tokens {
+		if h == hub {
+			delete(gs.tokens, token)
+		}
+	}
+}
+
+// join adds a connection to the hub. A player coming back within the grace
+// period keeps their seat, and any older connection of theirs is closed.
+func (h *gameHub) join(c *connection) {
+	h.broadcaster.RegisterClient(c.ctx, c.cancel, c.client)
+
+	h.mutex.Lock()
+	defer h.unlock()
+	for _, other := range h.connections {
+		if other.player.ID == c.player.ID {
+			other.cancel()
+		}
+	}
+	h.connections[c.client] = c
+
+	if timer, ok := h.absent[c.player.ID]; ok {
+		timer.Stop()
+		delete(h.absent, c.player.ID)
+		for _, other := range h.connections {
+			if other != c {
+				h.send(other, "", MessageTypePlayerReconnected, map[string]any{
+					"playerId": c.player.ID,
+					"seat":     h.game.PlayerIndex(c.player.ID),
+				})
+			}
+		}
+	}
+}
+
+// leave removes a connection once its last messages are written. If the game
+// is still going and the player has no other connection, the rest are told and
+// the player's seat is held for the grace period.
+func (h *gameHub) leave(c *connection) {
+	h.mutex.Lock()
+	delete(h.connections, c.client)
+	away := !h.game.IsOver() && !h.connected(c.player.ID)
+	if away {
+		h.absent[c.player.ID] = time.AfterFunc(h.grace, func() { h.abandon(c.player) })
+	}
+	h.mutex.Unlock()
+
+	c.flush()
+	h.broadcaster.UnregisterClient(c.client)
+
+	if away {
+		h.broadcast(MessageTypePlayerDisconnected, map[string]any{
+			"playerId": c.player.ID,
+			"seat":     h.game.PlayerIndex(c.player.ID),
+			"graceMs":  h.grace.Milliseconds(),
+		})
+	}
+}
+
+// abandon forfeits a player who did not come back in time
+func (h *gameHub) abandon(player *sticks.Player) {
+	h.mutex.Lock()
+	if _, ok := h.absent[player.ID]; !ok {
+		// they came back just as the timer fired
+		h.mutex.Unlock()
+		return
+	}
+	delete(h.absent, player.ID)
+
+	if err := h.game.Forfeit(player.ID, sticks.ResultAbandoned); err != nil {
+		log.Printf("Forfeiting %s in game %s: %v", player.ID, h.game.ID, err)
+	} else {
+		log.Printf("Player %s abandoned game %s", player.ID, h.game.ID)
+		h.publish(nil, "", MessageTypeGameState, h.game.Snapshot())
+	}
+	if h.game.IsOver() {
+		h.finish()
+	}
+	h.unlock()
+
+	h.release(h)
+}
+
+// watch publishes the changes the broker makes to the game, such as its bot
+// moving after a flag falls, and announces the result of a game the broker ended, by a flag falling or the
+// game timing out, once the session is done
+func (h *gameHub) watch(ctx context.Context, session *sticks.GameSession) {
+	for {
+		select {
+		case <-session.Changed:
+			h.mutex.Lock()
+			if !h.finished {
+				h.publish(nil, "", MessageTypeGameState, h.game.Snapshot())
+				if h.game.IsOver() {
+					h.finish()
+				}
+			}
+			h.unlock()
+		case <-session.Done:
+			h.mutex.Lock()
+			defer h.unlock()
+			if h.finished || !h.game.IsOver() {
+				return
+			}
+			h.publish(nil, "", MessageTypeGameState, h.game.Snapshot())
+			h.finish()
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// connected reports whether a player has a connection to the hub without
+// locking
+func (h *gameHub) connected(playerID string) bool {
+	for _, c := range h.connections {
+		if c.player.ID == playerID {
+			return true
+		}
+	}
+	return false
+}
+
+// empty reports whether the hub has no connections and nobody it is holding a
+// seat for
+func (h *gameHub) empty() bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return len(h.connections) == 0 && len(h.absent) == 0
+}
+
+// handle acts on a message from one of the hub's connections. Replies carry
+// the message's ID, the copies other players get do not.
+func (h *gameHub) handle(c *connection, analyzer *sticks.Analyzer, broker *sticks.GameBroker, msg Message) error {
+	payload, err := decode(msg)
+	if err != nil {
+		return err
+	}
+
+	h.mutex.Lock()
+	defer h.unlock()
+
+	switch data := payload.(type) {
+	case *PingMessageData:
+		h.send(c, msg.ID, MessageTypePong, nil)
+		return nil
+	case *ChatMessageData:
+		data.From = c.player.ID
+		h.publish(c, msg.ID, MessageTypeChat, data)
+		return nil
+	case *HintMessageData:
+		moves, err := hint(c, analyzer, h.game)
+		if err != nil {
+			return err
+		}
+		h.send(c, msg.ID, MessageTypeHint, map[string]any{
+			"moves": moves,
+		})
+		return nil
+	}
+
+	// Process game actions. A move made after the mover's flag fell is
+	// refused but still ends the game, so everyone hears about it.
+	over := h.game.IsOver()
+	if err := processGameAction(h.game, c.player, payload); err != nil {
+		if over || !h.game.IsOver() {
+			return err
+		}
+		h.send(c, msg.ID, MessageTypeError, errorData(err))
+		h.publish(nil, "", MessageTypeGameState, h.game.Snapshot())
+		h.finish()
+		return nil
+	}
+
+	// Tell everyone about draw offers and refusals before the new state
+	switch data := payload.(type) {
+	case *DrawOfferMessageData:
+		data.From = c.player.ID
+		h.publish(c, msg.ID, MessageTypeDrawOffer, data)
+	case *DrawDeclineMessageData:
+		data.From = c.player.ID
+		h.publish(c, msg.ID, MessageTypeDrawDecline, data)
+	}
+
+	// Let a bot opponent reply before reporting the new state
+	if session, ok := broker.GetGameSession(h.game.ID); ok {
+		declined, err := session.DeclineBotDraw()
+		if err != nil {
+			log.Printf("Bot error in game %s: %v", h.game.ID, err)
+		}
+		if declined {
+			h.publish(nil, "", MessageTypeDrawDecline, &DrawDeclineMessageData{From: session.BotPlayer.ID})
+		}
+		if _, err := session.PlayBotTurn(); err != nil {
+			log.Printf("Bot error in game %s: %v", h.game.ID, err)
+		}
+	}
+
+	h.publish(c, msg.ID, MessageTypeGameState, h.game.Snapshot())
+	if h.game.IsOver() {
+		h.finish()
+	}
+	return nil
+}
+
+// unlock releases the hub, then sends the updates queued while it was held.
+// Updates still go out in order, but a connection that is slow to take them
+// no longer holds up players joining, leaving or forfeiting.
+func (h *gameHub) unlock() {
+	outbox := h.outbox
+	h.outbox = nil
+	h.sending.Lock()
+	defer h.sending.Unlock()
+	h.mutex.Unlock()
+
+	for _, deliver := range outbox {
+		deliver()
+	}
+}
+
+// send queues a message for a connection without locking. A connection that
+// does not take it in time is dropped.
+func (h *gameHub) send(c *connection, id string, msgType MessageType, data any) {
+	payload, err := encode(id, msgType, data)
+	if err != nil {
+		log.Printf("Error encoding %s message: %v", msgType, err)
+		return
+	}
+	h.outbox = append(h.outbox, func() {
+		if c.ctx.Err() != nil {
+			return
+		}
+		if _, err := c.client.Write(payload); err != nil {
+			log.Printf("Dropping %s from game %s: %v", c.player.ID, h.game.ID, err)
+			c.cancel()
+		}
+	})
+}
+
+// publish queues a message for every connection, with id only on the copy
+// for from, the connection that asked for it
+func (h *gameHub) publish(from *connection, id string, msgType MessageType, data any) {
+	for _, c := range h.connections {
+		if c == from {
+			h.send(c, id, msgType, data)
+		} else {
+			h.send(c, "", msgType, data)
+		}
+	}
+}
+
+// broadcast sends a message to every connection
+func (h *gameHub) broadcast(msgType MessageType, data any) {
+	payload, err := encode("", msgType, data)
+	if err != nil {
+		log.Printf("Error encoding %s message: %v", msgType, err)
+		return
+	}
+	if err := h.broadcaster.Broadcast(payload); err != nil {
+		log.Printf("Error broadcasting %s in game %s: %v", msgType, h.game.ID, err)
+	}
+}
+
+// finish announces the result and ends every connection's session once the
+// announcement is queued. Nobody is waited for after the game is over.
+func (h *gameHub) finish() {
+	h.finished = true
+	for id, timer := range h.absent {
+		timer.Stop()
+		delete(h.absent, id)
+	}
+
+	snapshot := h.game.Snapshot()
+	h.publish(nil, "", MessageTypeGameEnd, map[string]any{
+		"winner": snapshot.Winner,
+		"state":  snapshot.State,
+		"result": snapshot.Result,
+	})
+	for _, c := range h.connections {
+		h.outbox = append(h.outbox, c.cancel)
+	}
+}
+
+// encode wraps data in an envelope. nil data leaves the data out.
+func encode(id string, msgType MessageType, data any) ([]byte, error) {
+	msg := Message{Type: msgType, ID: id, Data: nil}
+	if data != nil {
+		payload, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		msg.Data = payload
+	}
+	return json.Marshal(msg)
+}
@@ -0,0 +1,366 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	gwebsocket "github.com/gorilla/websocket"
+	"github.com/tkahng/sticks"
+	"github.com/tkahng/sticks/websocket"
+)
+
+// dialPlayer connects to the test server as playerID, with query added to
+// the URL
+func dialPlayer(t *testing.T, url, playerID, query string) *gwebsocket.Conn {
+	t.Helper()
+	header := http.Header{}
+	header.Set("Cookie", playerIDCookie+"="+signPlayerID(testCookieKey, playerID))
+	conn, _, err := gwebsocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(url, "http")+"/api/ws"+query, header)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+// matched is the data of a game_matched message
+type matched struct {
+	Seat        int    `json:"seat"`
+	ResumeToken string `json:"resumeToken"`
+}
+
+// startGame matches two players on a new test server and returns their
+// connections and game_matched data by seat
+func startGame(t *testing.T, options ...Option) (string, [2]*gwebsocket.Conn, [2]matched) {
+	t.Helper()
+	url := newTestServer(t, options...)
+
+	players := []*gwebsocket.Conn{dialPlayer(t, url, "alice", ""), dialPlayer(t, url, "bob", "")}
+	var seats [2]*gwebsocket.Conn
+	var matches [2]matched
+	for _, conn := range players {
+		var m matched
+		if err := json.Unmarshal(expectMessage(t, conn, MessageTypeGameMatched).Data, &m); err != nil {
+			t.Fatalf("json.Unmarshal() error = %v", err)
+		}
+		expectMessage(t, conn, MessageTypeGameState)
+		seats[m.Seat], matches[m.Seat] = conn, m
+	}
+	return url, seats, matches
+}
+
+// expectMessage reads messages until one of msgType arrives
+func expectMessage(t *testing.T, conn *gwebsocket.Conn, msgType MessageType) Message {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var msg Message
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("reading %s: %v", msgType, err)
+		}
+		if msg.Type == msgType {
+			return msg
+		}
+	}
+}
+
+func TestGameHub_PushesToEveryPlayer(t *testing.T) {
+	_, seats, _ := startGame(t)
+	mover, waiter := seats[0], seats[1]
+
+	target := 1
+	data, _ := json.Marshal(AttackMessageData{WithLeft: true, Target: &target, AttackLeft: true})
+	if err := mover.WriteJSON(Message{Type: MessageTypeAttack, ID: "move-1", Data: data}); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+
+	if reply := expectMessage(t, mover, MessageTypeGameState); reply.ID != "move-1" {
+		t.Errorf("mover's game_state id = %q, want move-1", reply.ID)
+	}
+	pushed := expectMessage(t, waiter, MessageTypeGameState)
+	if pushed.ID != "" {
+		t.Errorf("opponent's game_state id = %q, want none", pushed.ID)
+	}
+	var snapshot sticks.GameSnapshot
+	if err := json.Unmarshal(pushed.Data, &snapshot); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if snapshot.MoveCount != 1 || snapshot.CurrentTurn != 1 {
+		t.Errorf("opponent's snapshot = %+v, want one move played", snapshot)
+	}
+	for _, p := range snapshot.Players {
+		if p.Rating != 1500 {
+			t.Errorf("player %s rating = %d, want the starting 1500", p.ID, p.Rating)
+		}
+	}
+
+	_ = mover.Close()
+	expectMessage(t, waiter, MessageTypePlayerDisconnected)
+}
+
+func TestGameHub_Resume(t *testing.T) {
+	url, seats, matches := startGame(t)
+
+	_ = seats[0].Close()
+	expectMessage(t, seats[1], MessageTypePlayerDisconnected)
+
+	// the token works from any connection, whatever its cookie
+	conn := dialPlayer(t, url, "new-device", "?resume="+matches[0].ResumeToken)
+	var m matched
+	if err := json.Unmarshal(expectMessage(t, conn, MessageTypeGameMatched).Data, &m); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if m.Seat != 0 {
+		t.Errorf("resumed seat = %d, want 0", m.Seat)
+	}
+	expectMessage(t, conn, MessageTypeGameState)
+	expectMessage(t, seats[1], MessageTypePlayerReconnected)
+
+	bad := dialPlayer(t, url, "mallory", "?resume=nonsense")
+	var data ErrorMessageData
+	if err := json.Unmarshal(expectMessage(t, bad, MessageTypeError).Data, &data); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if data.Code != ErrorCodeNotFound {
+		t.Errorf("unknown token error code = %s, want %s", data.Code, ErrorCodeNotFound)
+	}
+}
+
+func TestGameHub_ForfeitAfterGrace(t *testing.T) {
+	_, seats, _ := startGame(t, WithReconnectGrace(100*time.Millisecond))
+
+	_ = seats[0].Close()
+	expectMessage(t, seats[1], MessageTypePlayerDisconnected)
+
+	var end struct {
+		Winner string              `json:"winner"`
+		Result sticks.ResultReason `json:"result"`
+	}
+	if err := json.Unmarshal(expectMessage(t, seats[1], MessageTypeGameEnd).Data, &end); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if end.Result != sticks.ResultAbandoned || end.Winner == "" {
+		t.Errorf("game end = %+v, want a win by abandonment", end)
+	}
+}
+
+func TestGameHub_DrawAgreement(t *testing.T) {
+	_, seats, _ := startGame(t)
+
+	if err := seats[1].WriteJSON(Message{Type: MessageTypeDrawOffer, ID: "offer", Data: nil}); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	var offer DrawOfferMessageData
+	if err := json.Unmarshal(expectMessage(t, seats[0], MessageTypeDrawOffer).Data, &offer); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if offer.From == "" {
+		t.Errorf("relayed draw offer has no sender")
+	}
+
+	if err := seats[0].WriteJSON(Message{Type: MessageTypeDrawAccept, ID: "accept", Data: nil}); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	var end struct {
+		State  sticks.GameState    `json:"state"`
+		Result sticks.ResultReason `json:"result"`
+	}
+	if err := json.Unmarshal(expectMessage(t, seats[1], MessageTypeGameEnd).Data, &end); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if end.State != sticks.GameStateDraw || end.Result != sticks.ResultAgreement {
+		t.Errorf("game end = %+v, want a draw by agreement", end)
+	}
+}
+
+func TestGameHub_Resign(t *testing.T) {
+	_, seats, _ := startGame(t)
+
+	// resigning does not wait for your turn
+	if err := seats[1].WriteJSON(Message{Type: MessageTypeResign, ID: "resign", Data: nil}); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	var end struct {
+		Winner string              `json:"winner"`
+		Result sticks.ResultReason `json:"result"`
+	}
+	if err := json.Unmarshal(expectMessage(t, seats[0], MessageTypeGameEnd).Data, &end); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if end.Result != sticks.ResultResignation || end.Winner == "" {
+		t.Errorf("game end = %+v, want a win by resignation", end)
+	}
+}
+
+func TestGameHub_AbortAfterFirstMove(t *testing.T) {
+	_, seats, _ := startGame(t)
+
+	target := 1
+	data, _ := json.Marshal(AttackMessageData{WithLeft: true, Target: &target, AttackLeft: true})
+	if err := seats[0].WriteJSON(Message{Type: MessageTypeAttack, ID: "move-1", Data: data}); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	expectMessage(t, seats[1], MessageTypeGameState)
+
+	if err := seats[1].WriteJSON(Message{Type: MessageTypeAbort, ID: "abort", Data: nil}); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	var reply ErrorMessageData
+	if err := json.Unmarshal(expectMessage(t, seats[1], MessageTypeError).Data, &reply); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if reply.Code != ErrorCodeNotAllowed {
+		t.Errorf("abort error code = %s, want %s", reply.Code, ErrorCodeNotAllowed)
+	}
+}
+
+func TestGameHub_FlagFall(t *testing.T) {
+	tc := &sticks.TimeControl{Base: 300 * time.Millisecond, Increment: 0, PerMove: 0}
+	_, seats, _ := startGame(t, WithBrokerOptions(sticks.WithTimeControl(tc)))
+
+	// nobody moves, so the broker flags the first player
+	for i, conn := range seats {
+		var end struct {
+			Winner string              `json:"winner"`
+			Result sticks.ResultReason `json:"result"`
+		}
+		if err := json.Unmarshal(expectMessage(t, conn, MessageTypeGameEnd).Data, &end); err != nil {
+			t.Fatalf("json.Unmarshal() error = %v", err)
+		}
+		if end.Result != sticks.ResultFlag || end.Winner == "" {
+			t.Errorf("seat %d game end = %+v, want a win on time", i, end)
+		}
+	}
+}
+
+// connPair opens a WebSocket connection and returns its server and client
+// ends
+func connPair(t *testing.T) (*gwebsocket.Conn, *gwebsocket.Conn) {
+	t.Helper()
+	accepted := make(chan *gwebsocket.Conn, 1)
+	upgrader := gwebsocket.Upgrader{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Upgrade() error = %v", err)
+			return
+		}
+		accepted <- conn
+	}))
+	t.Cleanup(ts.Close)
+
+	client, _, err := gwebsocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return <-accepted, client
+}
+
+func TestGameHub_SlowConnection(t *testing.T) {
+	alice, bob := sticks.NewPlayer("alice", ""), sticks.NewPlayer("bob", "")
+	game := sticks.NewGame("slow", sticks.DefaultRuleset())
+	for _, player := range []*sticks.Player{alice, bob} {
+		if err := game.AddPlayer(player); err != nil {
+			t.Fatalf("Game.AddPlayer() error = %v", err)
+		}
+	}
+	if err := game.StartGame(); err != nil {
+		t.Fatalf("Game.StartGame() error = %v", err)
+	}
+	hub := newGameHub(game, time.Minute, func(*gameHub) {}, nil)
+	t.Cleanup(hub.stop)
+
+	conn, reader := connPair(t)
+	fast := newConnection(conn, bob)
+	t.Cleanup(fast.close)
+	hub.join(fast)
+
+	// alice's writer never runs, so nothing drains her queue
+	conn, _ = connPair(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	slow := &connection{
+		client:   websocket.NewClient(conn),
+		player:   alice,
+		ctx:      ctx,
+		cancel:   cancel,
+		written:  make(chan struct{}),
+		received: make(chan received),
+	}
+	close(slow.written)
+	slow.client.SetWriteTimeout(50 * time.Millisecond)
+	hub.join(slow)
+
+	const chats = 40
+	data, _ := json.Marshal(ChatMessageData{Text: "hello"})
+	for range chats {
+		if err := hub.handle(fast, nil, nil, Message{Type: MessageTypeChat, ID: "", Data: data}); err != nil {
+			t.Fatalf("gameHub.handle() error = %v", err)
+		}
+	}
+	for range chats {
+		expectMessage(t, reader, MessageTypeChat)
+	}
+
+	select {
+	case <-slow.ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("the connection that stopped reading was not dropped")
+	}
+
+	left := make(chan struct{})
+	go func() {
+		hub.leave(slow)
+		close(left)
+	}()
+	select {
+	case <-left:
+	case <-time.After(time.Second):
+		t.Fatal("gameHub.leave() blocked behind the slow connection")
+	}
+}
+
+func TestGameHub_BotMovesAfterFlag(t *testing.T) {
+	backfill := sticks.NewBotBackfill(sticks.NewFIFOMatchmaker(), 0, sticks.BotDifficultyEasy)
+	tc := &sticks.TimeControl{Base: 500 * time.Millisecond, Increment: 0, PerMove: 0}
+	url := newTestServer(t, WithBrokerOptions(sticks.WithMatchmaker(backfill), sticks.WithTimeControl(tc)))
+
+	// alice and bob get a bot for the third seat
+	var seats [2]*gwebsocket.Conn
+	for _, conn := range []*gwebsocket.Conn{dialPlayer(t, url, "alice", "?players=3"), dialPlayer(t, url, "bob", "?players=3")} {
+		var m matched
+		if err := json.Unmarshal(expectMessage(t, conn, MessageTypeGameMatched).Data, &m); err != nil {
+			t.Fatalf("json.Unmarshal() error = %v", err)
+		}
+		expectMessage(t, conn, MessageTypeGameState)
+		seats[m.Seat] = conn
+	}
+
+	// the first player moves and the second runs out of time, which leaves
+	// the bot to move without anyone moving before it
+	target := 1
+	data, _ := json.Marshal(AttackMessageData{WithLeft: true, Target: &target, AttackLeft: true})
+	if err := seats[0].WriteJSON(Message{Type: MessageTypeAttack, ID: "", Data: data}); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	for i, conn := range seats {
+		for {
+			var snapshot sticks.GameSnapshot
+			if err := json.Unmarshal(expectMessage(t, conn, MessageTypeGameState).Data, &snapshot); err != nil {
+				t.Fatalf("json.Unmarshal() error = %v", err)
+			}
+			if snapshot.MoveCount < 2 {
+				continue
+			}
+			if snapshot.Players[1].Alive || snapshot.CurrentTurn != 0 {
+				t.Errorf("seat %d game state = %+v, want the bot to have moved after seat 1 flagged", i, snapshot)
+			}
+			break
+		}
+	}
+}
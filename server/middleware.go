@@ -2,8 +2,14 @@ package server
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -31,22 +37,29 @@ func Cors(h http.Handler) http.Handler {
 	})
 }
 
-func PlayerID(h http.Handler) http.Handler {
+// playerIDCookie is the cookie holding a player's signed ID
+const playerIDCookie = "player_id"
+
+// PlayerID gives every request a player ID, read from the player_id cookie or
+// newly issued. The cookie is signed with key, so players keep their ID and
+// rating between visits but cannot claim somebody else's.
+func PlayerID(key []byte, h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var playerID string
-		playerIDCookie, err := r.Cookie("player_id")
+		cookie, err := r.Cookie(playerIDCookie)
+		if err == nil {
+			playerID, err = verifyPlayerID(key, cookie.Value)
+		}
 		if err != nil {
-			slog.Error("Failed to get player ID cookie", slog.Any("error", err))
-			playerID = generatePlayerID()
-		} else if playerIDCookie.Value == "" {
+			if !errors.Is(err, http.ErrNoCookie) {
+				slog.Warn("Ignoring player ID cookie", slog.Any("error", err))
+			}
 			playerID = generatePlayerID()
-		} else {
-			playerID = playerIDCookie.Value
 		}
 		// nolint:exhaustruct
-		cookie := &http.Cookie{
-			Name:     "my_session_cookie",
-			Value:    playerID,
+		cookie = &http.Cookie{
+			Name:     playerIDCookie,
+			Value:    signPlayerID(key, playerID),
 			Expires:  time.Now().Add(24 * time.Hour), // Cookie expires in 24 hours
 			Path:     "/",                            // Valid for all paths
 			HttpOnly: true,                           // Accessible only via HTTP(S), not JavaScript
@@ -63,3 +76,30 @@ func PlayerID(h http.Handler) http.Handler {
 		h.ServeHTTP(w, r)
 	})
 }
+
+// signPlayerID returns the cookie value for a player ID: the ID and its
+// signature, separated by a dot
+func signPlayerID(key []byte, playerID string) string {
+	return playerID + "." + base64.RawURLEncoding.EncodeToString(playerIDMAC(key, playerID))
+}
+
+// verifyPlayerID returns the player ID in a cookie value made by signPlayerID
+// with the same key
+func verifyPlayerID(key []byte, value string) (string, error) {
+	i := strings.LastIndexByte(value, '.')
+	if i <= 0 {
+		return "", fmt.Errorf("player ID cookie is not signed")
+	}
+	playerID := value[:i]
+	signature, err := base64.RawURLEncoding.DecodeString(value[i+1:])
+	if err != nil || !hmac.Equal(signature, playerIDMAC(key, playerID)) {
+		return "", fmt.Errorf("player ID cookie has a bad signature")
+	}
+	return playerID, nil
+}
+
+func playerIDMAC(key []byte, playerID string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(playerID))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,208 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/gorilla/websocket"
+	"github.com/tkahng/sticks"
+)
+
+type MessageType string
+
+// Messages sent by clients
+const (
+	MessageTypeAttack      MessageType = "attack"
+	MessageTypeSplit       MessageType = "split"
+	MessageTypeTransfer    MessageType = "transfer"
+	MessageTypeHint        MessageType = "hint" // also the reply, with the ranked moves
+	MessageTypeResign      MessageType = "resign"
+	MessageTypeDrawOffer   MessageType = "draw_offer" // also relayed to players, with the offerer
+	MessageTypeDrawAccept  MessageType = "draw_accept"
+	MessageTypeDrawDecline MessageType = "draw_decline" // also relayed to players, with the decliner
+	MessageTypeAbort       MessageType = "abort"        // only before the first move
+	MessageTypeChat        MessageType = "chat"         // also relayed to players, with the sender
+	MessageTypePing        MessageType = "ping"
+)
+
+// Messages sent by the server
+const (
+	MessageTypeGameMatched MessageType = "game_matched"
+	MessageTypeGameState   MessageType = "game_state"
+	MessageTypeGameEnd     MessageType = "game_end"
+	MessageTypePuzzleStart MessageType = "puzzle_start"
+	MessageTypePuzzleEnd   MessageType = "puzzle_end"
+	MessageTypePong        MessageType = "pong"
+	// MessageTypeQueueStatus tells a player waiting for a match where they are
+	// in the queue
+	MessageTypeQueueStatus MessageType = "queue_status"
+	// MessageTypePlayerDisconnected tells the rest of a game that a player's
+	// connection dropped
+	MessageTypePlayerDisconnected MessageType = "player_disconnected"
+	MessageTypePlayerReconnected  MessageType = "player_reconnected"
+	MessageTypeError              MessageType = "error"
+)
+
+// maxChatLength caps a chat message, in bytes
+const maxChatLength = 500
+
+type (
+	// Message is the envelope for every message in either direction. The
+	// server echoes a request's ID in its replies so clients can match them.
+	Message struct {
+		Type MessageType     `json:"type"`
+		ID   string          `json:"id,omitempty"`
+		Data json.RawMessage `json:"data,omitempty"` // decoded by type once the message type is known
+	}
+	AttackMessageData struct {
+		WithLeft   bool `json:"with_left"`
+		Target     *int `json:"target,omitempty"` // seat to attack, defaults to the next opponent
+		AttackLeft bool `json:"attack_left"`
+	}
+	SplitMessageData struct {
+		WithLeft bool `json:"with_left"`
+		Points   int  `json:"points"`
+	}
+	TransferMessageData struct {
+		WithLeft bool `json:"with_left"`
+		Target   int  `json:"target"` // seat of the teammate receiving the points
+		ToLeft   bool `json:"to_left"`
+		Points   int  `json:"points"`
+	}
+	HintMessageData      struct{}
+	ResignMessageData    struct{}
+	DrawOfferMessageData struct {
+		From string `json:"from,omitempty"` // set by the server when relaying
+	}
+	DrawAcceptMessageData  struct{}
+	DrawDeclineMessageData struct {
+		From string `json:"from,omitempty"` // set by the server when relaying
+	}
+	AbortMessageData struct{}
+	ChatMessageData  struct {
+		From string `json:"from,omitempty"` // set by the server when relaying
+		Text string `json:"text"`
+	}
+	PingMessageData  struct{}
+	ErrorMessageData struct {
+		Code    ErrorCode `json:"code"`
+		Message string    `json:"message"`
+	}
+)
+
+// ErrorCode tells clients what went wrong without parsing error text
+type ErrorCode string
+
+const (
+	ErrorCodeBadMessage  ErrorCode = "bad_message"  // the message or its data could not be decoded
+	ErrorCodeUnknownType ErrorCode = "unknown_type" // the message type is not one clients may send
+	ErrorCodeNotYourTurn ErrorCode = "not_your_turn"
+	ErrorCodeIllegalMove ErrorCode = "illegal_move"
+	ErrorCodeNotAllowed  ErrorCode = "not_allowed" // a resign, draw or abort the game does not allow now
+	ErrorCodeGameOver    ErrorCode = "game_over"
+	ErrorCodeUnavailable ErrorCode = "unavailable" // the action is not offered in this game or server
+	ErrorCodeNotFound    ErrorCode = "not_found"
+	ErrorCodeMatchmaking ErrorCode = "matchmaking_failed"
+	ErrorCodeTimeout     ErrorCode = "timeout"
+	ErrorCodeInternal    ErrorCode = "internal"
+)
+
+// Error is an error reported to the client with its code
+type Error struct {
+	Code    ErrorCode
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func newError(code ErrorCode, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// decoders make an empty payload for each message type clients may send
+var decoders = map[MessageType]func() any{
+	MessageTypeAttack:      func() any { return &AttackMessageData{} },
+	MessageTypeSplit:       func() any { return &SplitMessageData{} },
+	MessageTypeTransfer:    func() any { return &TransferMessageData{} },
+	MessageTypeHint:        func() any { return &HintMessageData{} },
+	MessageTypeResign:      func() any { return &ResignMessageData{} },
+	MessageTypeDrawOffer:   func() any { return &DrawOfferMessageData{} },
+	MessageTypeDrawAccept:  func() any { return &DrawAcceptMessageData{} },
+	MessageTypeDrawDecline: func() any { return &DrawDeclineMessageData{} },
+	MessageTypeAbort:       func() any { return &AbortMessageData{} },
+	MessageTypeChat:        func() any { return &ChatMessageData{} },
+	MessageTypePing:        func() any { return &PingMessageData{} },
+}
+
+// readMessage reads the next envelope from conn. A message that cannot be
+// decoded is returned as an *Error, any other error is from the connection.
+func readMessage(conn *websocket.Conn) (Message, error) {
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		return Message{}, err
+	}
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return Message{}, newError(ErrorCodeBadMessage, "invalid message: %v", err)
+	}
+	return msg, nil
+}
+
+// decode unmarshals a message's data into the payload for its type. Types
+// without fields may leave the data out.
+func decode(msg Message) (any, error) {
+	newPayload, ok := decoders[msg.Type]
+	if !ok {
+		return nil, newError(ErrorCodeUnknownType, "unknown message type %q", msg.Type)
+	}
+	payload := newPayload()
+	if len(msg.Data) > 0 {
+		if err := json.Unmarshal(msg.Data, payload); err != nil {
+			return nil, newError(ErrorCodeBadMessage, "invalid %s data: %v", msg.Type, err)
+		}
+	}
+	if chat, ok := payload.(*ChatMessageData); ok {
+		switch {
+		case chat.Text == "":
+			return nil, newError(ErrorCodeBadMessage, "chat message is empty")
+		case len(chat.Text) > maxChatLength:
+			return nil, newError(ErrorCodeBadMessage, "chat message is longer than %d bytes", maxChatLength)
+		}
+	}
+	return payload, nil
+}
+
+// moveFromPayload turns a decoded action into a move for the current player.
+// ok is false for payloads that are not moves.
+func moveFromPayload(game *sticks.Game, payload any) (move sticks.Move, ok bool, err error) {
+	switch data := payload.(type) {
+	case *AttackMessageData:
+		if data.Target != nil {
+			return sticks.AttackMove(*data.Target, data.WithLeft, data.AttackLeft), true, nil
+		}
+		opponent := game.GetOpponent()
+		if opponent == nil {
+			return sticks.Move{}, true, newError(ErrorCodeIllegalMove, "no opponent left to attack")
+		}
+		return sticks.AttackMove(game.PlayerIndex(opponent.ID), data.WithLeft, data.AttackLeft), true, nil
+	case *SplitMessageData:
+		return sticks.SplitMove(data.WithLeft, data.Points), true, nil
+	case *TransferMessageData:
+		return sticks.TransferMove(data.Target, data.WithLeft, data.ToLeft, data.Points), true, nil
+	default:
+		return sticks.Move{}, false, nil
+	}
+}
+
+// errorData describes err for the client, treating errors without a code as
+// internal
+func errorData(err error) ErrorMessageData {
+	var perr *Error
+	if errors.As(err, &perr) {
+		return ErrorMessageData{Code: perr.Code, Message: perr.Message}
+	}
+	return ErrorMessageData{Code: ErrorCodeInternal, Message: err.Error()}
+}
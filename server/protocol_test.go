@@ -0,0 +1,83 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	target := 1
+	tests := []struct {
+		name     string
+		msg      Message
+		want     any
+		wantCode ErrorCode
+	}{
+		{
+			name: "attack",
+			msg:  Message{Type: MessageTypeAttack, ID: "1", Data: json.RawMessage(`{"with_left":true,"target":1}`)},
+			want: &AttackMessageData{WithLeft: true, Target: &target, AttackLeft: false},
+		},
+		{
+			name: "split",
+			msg:  Message{Type: MessageTypeSplit, ID: "", Data: json.RawMessage(`{"with_left":false,"points":2}`)},
+			want: &SplitMessageData{WithLeft: false, Points: 2},
+		},
+		{
+			name: "ping without data",
+			msg:  Message{Type: MessageTypePing, ID: "7", Data: nil},
+			want: &PingMessageData{},
+		},
+		{
+			name: "resign",
+			msg:  Message{Type: MessageTypeResign, ID: "", Data: json.RawMessage(`{}`)},
+			want: &ResignMessageData{},
+		},
+		{
+			name: "chat",
+			msg:  Message{Type: MessageTypeChat, ID: "", Data: json.RawMessage(`{"text":"good game"}`)},
+			want: &ChatMessageData{From: "", Text: "good game"},
+		},
+		{
+			name:     "empty chat",
+			msg:      Message{Type: MessageTypeChat, ID: "", Data: json.RawMessage(`{"text":""}`)},
+			wantCode: ErrorCodeBadMessage,
+		},
+		{
+			name:     "long chat",
+			msg:      Message{Type: MessageTypeChat, ID: "", Data: json.RawMessage(`{"text":"` + strings.Repeat("a", maxChatLength+1) + `"}`)},
+			wantCode: ErrorCodeBadMessage,
+		},
+		{
+			name:     "wrong data type",
+			msg:      Message{Type: MessageTypeSplit, ID: "", Data: json.RawMessage(`{"points":"two"}`)},
+			wantCode: ErrorCodeBadMessage,
+		},
+		{
+			name:     "server message type",
+			msg:      Message{Type: MessageTypeGameState, ID: "", Data: nil},
+			wantCode: ErrorCodeUnknownType,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decode(tt.msg)
+			if tt.wantCode != "" {
+				var perr *Error
+				if !errors.As(err, &perr) || perr.Code != tt.wantCode {
+					t.Fatalf("decode() error = %v, want code %s", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decode() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decode() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
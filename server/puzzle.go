@@ -0,0 +1,110 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/tkahng/sticks"
+)
+
+// defaultPuzzleMoves is the longest win generated when no puzzle set is loaded
+const defaultPuzzleMoves = 3
+
+// LoadPuzzles replaces the generated puzzles with a saved puzzle set. The set
+// must be for the default rules, which are the only ones solved for puzzles.
+func (gs *GameServer) LoadPuzzles(r io.Reader) error {
+	set, err := sticks.ReadPuzzleSet(r)
+	if err != nil {
+		return err
+	}
+	if gs.tablebase == nil || set.Rules != gs.tablebase.Rules {
+		return fmt.Errorf("puzzle set rules do not match the server's tablebase")
+	}
+	gs.puzzles = set
+	return nil
+}
+
+// handlePuzzle plays a puzzle with the player against a perfect defender.
+// The id "daily" picks the puzzle of the day.
+func (gs *GameServer) handlePuzzle(conn *connection, id string) {
+	if gs.puzzles == nil || gs.tablebase == nil {
+		sendError(conn.client, "", newError(ErrorCodeUnavailable, "puzzles are not available"))
+		return
+	}
+
+	var puzzle sticks.Puzzle
+	var ok bool
+	if id == "daily" {
+		puzzle, ok = gs.puzzles.Daily(time.Now())
+	} else {
+		puzzle, ok = gs.puzzles.Find(id)
+	}
+	if !ok {
+		sendError(conn.client, "", newError(ErrorCodeNotFound, "unknown puzzle %q", id))
+		return
+	}
+
+	attempt, err := sticks.NewPuzzleAttempt(puzzle, gs.tablebase, conn.player)
+	if err != nil {
+		sendError(conn.client, "", err)
+		return
+	}
+
+	send(conn.client, "", MessageTypePuzzleStart, map[string]any{
+		"puzzleId": puzzle.ID,
+		"moves":    puzzle.Moves,
+		"seat":     puzzle.Position.Turn,
+	})
+	send(conn.client, "", MessageTypeGameState, attempt.Game.Snapshot())
+
+	for attempt.Status == sticks.PuzzleStatusPlaying {
+		msg, err := conn.read()
+		if err != nil {
+			var perr *Error
+			if errors.As(err, &perr) {
+				sendError(conn.client, "", err)
+				continue
+			}
+			log.Printf("WebSocket read error: %v", err)
+			return
+		}
+		if err := gs.handlePuzzleMessage(conn, attempt, msg); err != nil {
+			sendError(conn.client, msg.ID, err)
+		}
+	}
+
+	send(conn.client, "", MessageTypePuzzleEnd, map[string]any{
+		"puzzleId": puzzle.ID,
+		"status":   attempt.Status,
+		"solution": puzzle.Solution,
+	})
+}
+
+// handlePuzzleMessage acts on one message during a puzzle. Only moves and
+// pings make sense against the puzzle's defender.
+func (gs *GameServer) handlePuzzleMessage(conn *connection, attempt *sticks.PuzzleAttempt, msg Message) error {
+	payload, err := decode(msg)
+	if err != nil {
+		return err
+	}
+	if _, ok := payload.(*PingMessageData); ok {
+		send(conn.client, msg.ID, MessageTypePong, nil)
+		return nil
+	}
+
+	move, ok, err := moveFromPayload(attempt.Game, payload)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return newError(ErrorCodeUnavailable, "%s is not available in puzzles", msg.Type)
+	}
+	if err := attempt.Play(move); err != nil {
+		return newError(ErrorCodeIllegalMove, "%v", err)
+	}
+	send(conn.client, msg.ID, MessageTypeGameState, attempt.Game.Snapshot())
+	return nil
+}
@@ -65,12 +65,13 @@ func (gs *GameServer) HandleHome(w http.ResponseWriter, r *http.Request) {
     </div>
 
     <script>
-        const ws = new WebSocket('ws://localhost:8080/ws');
+        const ws = new WebSocket((location.protocol === 'https:' ? 'wss://' : 'ws://') + location.host + '/api/ws');
         const status = document.getElementById('status');
-        let gameState = null;
+        let gameState = null; // the latest game snapshot
         let selectedHand = null;
         let isMyTurn = false;
-        let myPlayerNumber = null;
+        let mySeat = null; // from game_matched
+        let opponentSeat = null;
 
         ws.onopen = function() {
             status.textContent = 'Connected - Waiting for opponent...';
@@ -87,10 +88,9 @@ func (gs *GameServer) HandleHome(w http.ResponseWriter, r *http.Request) {
 
         function handleMessage(message) {
             switch(message.type) {
-                case 'game_start':
-                    gameState = message.data;
+                case 'game_matched':
+                    mySeat = message.data.seat;
                     status.textContent = 'Game started!';
-                    updateGameDisplay();
                     break;
                 case 'game_state':
                     gameState = message.data;
@@ -98,51 +98,61 @@ func (gs *GameServer) HandleHome(w http.ResponseWriter, r *http.Request) {
                     updateGameDisplay();
                     break;
                 case 'error':
-                    status.textContent = 'Error: ' + msg.data;
+                    status.textContent = 'Error: ' + message.data.message;
                     alert(status.textContent);
                     break;
                 case 'game_end':
-                    gameState = message.data;
-                    updateGameDisplay();
-                    if (gameState.winner) {
-                        const isWinner = (myPlayerNumber === 1 && gameState.winner.id === gameState.player1.id) ||
-                                       (myPlayerNumber === 2 && gameState.winner.id === gameState.player2.id);
+                    if (message.data.winner) {
+                        const me = gameState.players[mySeat];
+                        const winner = gameState.players.find(p => p.id === message.data.winner);
+                        const isWinner = winner && winner.team === me.team;
                         status.textContent = isWinner ? 'You Win!' : 'You Lose!';
+                    } else {
+                        status.textContent = 'Game over: ' + message.data.result;
                     }
                     break;
             }
         }
 
-        function updateGameDisplay() {
-            if (!gameState) return;
-
-            // Determine which player is me
-            // For simplicity, assume player1 is always "me" in this demo
-            const me = gameState.player1;
-            const opponent = gameState.player2;
-            
-            myPlayerNumber = 1;
-            isMyTurn = gameState.currentTurn === 0;
+        // nextOpponent returns the seat of the first living player on another
+        // team after mine, in turn order
+        function nextOpponent() {
+            const players = gameState.players;
+            for (let i = 1; i < players.length; i++) {
+                const seat = (mySeat + i) % players.length;
+                if (players[seat].alive && players[seat].team !== players[mySeat].team) {
+                    return seat;
+                }
+            }
+            return null;
+        }
 
+        function updateGameDisplay() {
+            if (!gameState || mySeat === null) return;
 
-            updateHand('opp-left', opponent.leftHand);
-            updateHand('opp-right', opponent.rightHand);
+            const me = gameState.players[mySeat];
+            opponentSeat = nextOpponent();
+            isMyTurn = gameState.state === 'in_progress' && gameState.currentTurn === mySeat;
 
+            if (opponentSeat !== null) {
+                const opponent = gameState.players[opponentSeat];
+                updateHand('opp-left', opponent.leftHand);
+                updateHand('opp-right', opponent.rightHand);
+            }
 
             updateHand('player-left', me.leftHand);
             updateHand('player-right', me.rightHand);
 
-    
-            document.getElementById('turn-info').textContent = 
-                isMyTurn ? 'Your Turn' : 'Opponent\'s Turn';
+            document.getElementById('turn-info').textContent =
+                isMyTurn ? 'Your Turn' : 'Player ' + (gameState.currentTurn + 1) + '\'s Turn';
         }
 
         function updateHand(elementId, hand) {
             const element = document.getElementById(elementId);
             const pointsElement = document.getElementById(elementId + '-points');
-            
+
             pointsElement.textContent = hand.points;
-            
+
             if (hand.alive) {
                 element.className = 'hand alive';
             } else {
@@ -152,26 +162,25 @@ func (gs *GameServer) HandleHome(w http.ResponseWriter, r *http.Request) {
 
         // Add click handlers for hands
         document.getElementById('player-left').onclick = function() {
-            if (isMyTurn && gameState.player1.leftHand.alive) {
+            if (isMyTurn && gameState.players[mySeat].leftHand.alive) {
                 selectHand('player-left', true);
             }
         };
 
         document.getElementById('player-right').onclick = function() {
-            if (isMyTurn && gameState.player1.rightHand.alive) {
+            if (isMyTurn && gameState.players[mySeat].rightHand.alive) {
                 selectHand('player-right', false);
             }
         };
 
         document.getElementById('opp-left').onclick = function() {
-            console.log(isMyTurn, selectedHand, gameState.player2.leftHand.alive);
-            if (isMyTurn && selectedHand && gameState.player2.leftHand.alive) {
+            if (isMyTurn && selectedHand && gameState.players[opponentSeat].leftHand.alive) {
                 attack(selectedHand.isLeft, true);
             }
         };
 
         document.getElementById('opp-right').onclick = function() {
-            if (isMyTurn && selectedHand && gameState.player2.rightHand.alive) {
+            if (isMyTurn && selectedHand && gameState.players[opponentSeat].rightHand.alive) {
                 attack(selectedHand.isLeft, false);
             }
         };
@@ -189,14 +198,15 @@ func (gs *GameServer) HandleHome(w http.ResponseWriter, r *http.Request) {
             }
         }
 
-        function attack(attackerIsLeft, defenderIsLeft) {
+        function attack(withLeft, attackLeft) {
             if (!isMyTurn || !selectedHand) return;
 
             const message = {
                 type: 'attack',
                 data: {
-                    attackerIsLeft: attackerIsLeft,
-                    defenderIsLeft: defenderIsLeft
+                    with_left: withLeft,
+                    target: opponentSeat,
+                    attack_left: attackLeft
                 }
             };
 
@@ -207,26 +217,27 @@ func (gs *GameServer) HandleHome(w http.ResponseWriter, r *http.Request) {
         function showSplitModal() {
             if (!isMyTurn) return;
 
-            const leftPoints = gameState.player1.leftHand.points;
-            const rightPoints = gameState.player1.rightHand.points;
+            const me = gameState.players[mySeat];
+            const leftPoints = me.leftHand.points;
+            const rightPoints = me.rightHand.points;
             const total = leftPoints + rightPoints;
 
-            const newLeft = prompt('Enter new left hand points (current: ' + leftPoints + ', total: ' + total + '):');
-            if (newLeft === null) return;
+            const input = prompt('Enter new left hand points (current: ' + leftPoints + ', total: ' + total + '):');
+            if (input === null) return;
 
-            const newRight = total - parseInt(newLeft);
-            
-            if (newLeft < 0 || newRight < 0 || newLeft + newRight !== total) {
+            const newLeft = parseInt(input);
+            if (isNaN(newLeft) || newLeft < 0 || newLeft > total || newLeft === leftPoints) {
                 alert('Invalid split!');
                 return;
             }
 
+            // a split moves points from one hand to the other
+            const fromLeft = newLeft < leftPoints;
             const message = {
                 type: 'split',
                 data: {
-                    fromLeft: true,
-                    newLeftPoints: parseInt(newLeft),
-                    newRightPoints: newRight
+                    with_left: fromLeft,
+                    points: Math.abs(leftPoints - newLeft)
                 }
             };
 
@@ -1,60 +1,102 @@
 package server
 
 import (
+	"context"
+	"crypto/rand"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
-	"github.com/gorilla/websocket"
+	gwebsocket "github.com/gorilla/websocket"
 	"github.com/tkahng/sticks"
-)
-
-type MessageType string
-
-const (
-	MessageTypeAttack         MessageType = "attack"
-	MessageTypeSplit          MessageType = "split"
-	MessageTypeStateGameStart MessageType = "state_game_start"
-	MessageTypeGameState      MessageType = "state_game_state"
-	MessageTypeError          MessageType = "error"
-	MessageTypeGameEnd        MessageType = "game_end"
-)
-
-type (
-	Message struct {
-		Type MessageType `json:"type"`
-		Data any         `json:"data"`
-	}
-	AttackMessageData struct {
-		WithLeft   bool `json:"with_left"`
-		AttackLeft bool `json:"attack_left"`
-	}
-	SplitMessageData struct {
-		WithLeft bool `json:"with_left"`
-		Points   int  `json:"points"`
-	}
+	"github.com/tkahng/sticks/websocket"
 )
 
 // GameServer integrates the matchmaking system with HTTP/WebSocket
 type GameServer struct {
-	broker   *sticks.GameBroker
-	upgrader websocket.Upgrader
-	mux      *http.ServeMux
+	broker    *sticks.GameBroker
+	analyzer  *sticks.Analyzer  // answers hints in practice games
+	tablebase *sticks.Tablebase // default rules, nil if solving failed
+	puzzles   *sticks.PuzzleSet // served in puzzle mode, nil for none
+	hubs      map[string]*gameHub
+	tokens    map[string]*gameHub // resume tokens to the hub of their game
+	hubsMutex *sync.Mutex
+	// reconnectGrace is how long a dropped player's seat is held before they
+	// forfeit
+	reconnectGrace time.Duration
+	brokerOptions  []sticks.BrokerOption
+	cookieKey      []byte // signs player ID cookies
+	upgrader       gwebsocket.Upgrader
+	mux            *http.ServeMux
 }
 
 func (gs *GameServer) Hanlder() http.Handler {
 	return gs.mux
 }
 
+// defaultReconnectGrace is how long a dropped player has to come back
+const defaultReconnectGrace = 30 * time.Second
+
+// Option configures a GameServer
+type Option func(*GameServer)
+
+// WithReconnectGrace sets how long a player whose connection drops may take
+// to resume their game before they forfeit it
+func WithReconnectGrace(grace time.Duration) Option {
+	return func(gs *GameServer) {
+		gs.reconnectGrace = grace
+	}
+}
+
+// WithBrokerOptions configures the server's game broker, for example to pick
+// its matchmaker
+func WithBrokerOptions(options ...sticks.BrokerOption) Option {
+	return func(gs *GameServer) {
+		gs.brokerOptions = append(gs.brokerOptions, options...)
+	}
+}
+
+// WithCookieKey sets the key player ID cookies are signed with. Without one
+// the server picks a random key, and players get new IDs when it restarts.
+func WithCookieKey(key []byte) Option {
+	return func(gs *GameServer) {
+		gs.cookieKey = key
+	}
+}
+
 // NewGameServer creates a new game server
-func NewGameServer(maxConcurrentGames int) *GameServer {
-	broker := sticks.NewGameBroker(maxConcurrentGames)
+func NewGameServer(maxConcurrentGames int, options ...Option) *GameServer {
+	// Hints are exact for the default rules and searched for anything else
+	tb, err := sticks.Solve(sticks.DefaultRuleset())
+	if err != nil {
+		log.Printf("Solving the default rules failed, hints will be searched: %v", err)
+	}
+
+	// Puzzles come from the same tablebase until a puzzle set is loaded
+	var puzzles *sticks.PuzzleSet
+	if tb != nil {
+		if puzzles, err = sticks.GeneratePuzzles(tb, defaultPuzzleMoves); err != nil {
+			log.Printf("Generating puzzles failed: %v", err)
+		}
+	}
 
-	return &GameServer{
-		broker: broker,
-		upgrader: websocket.Upgrader{
+	gs := &GameServer{
+		broker:         nil, // once the options are in
+		analyzer:       sticks.NewAnalyzer(tb),
+		tablebase:      tb,
+		puzzles:        puzzles,
+		hubs:           make(map[string]*gameHub),
+		tokens:         make(map[string]*gameHub),
+		hubsMutex:      &sync.Mutex{},
+		reconnectGrace: defaultReconnectGrace,
+		brokerOptions:  nil,
+		cookieKey:      []byte(rand.Text()),
+		upgrader: gwebsocket.Upgrader{
 			ReadBufferSize:    1024,
 			WriteBufferSize:   1024,
 			HandshakeTimeout:  0,
@@ -66,6 +108,11 @@ func NewGameServer(maxConcurrentGames int) *GameServer {
 		},
 		mux: http.NewServeMux(),
 	}
+	for _, option := range options {
+		option(gs)
+	}
+	gs.broker = sticks.NewGameBroker(maxConcurrentGames, gs.brokerOptions...)
+	return gs
 }
 
 // Start starts the game server
@@ -82,130 +129,269 @@ func (gs *GameServer) Stop() {
 // setupRoutes configures HTTP routes
 func (gs *GameServer) setupRoutes() {
 	// gs.mux.HandleFunc("/", gs.handleHome)
-	gs.mux.HandleFunc("/api/ws", PlayerID(http.HandlerFunc(gs.handleWebSocket)).ServeHTTP)
+	gs.mux.HandleFunc("/api/ws", PlayerID(gs.cookieKey, http.HandlerFunc(gs.handleWebSocket)).ServeHTTP)
 	gs.mux.HandleFunc("/api/stats", gs.handleStats)
+	gs.mux.HandleFunc("GET /api/players/{id}", gs.handlePlayerProfile)
 	gs.mux.HandleFunc("/api/health", gs.handleHealth)
 }
 
 // handleWebSocket handles WebSocket connections for real-time gameplay
 func (gs *GameServer) handleWebSocket(w http.ResponseWriter, r *http.Request) {
-	conn, err := gs.upgrader.Upgrade(w, r, nil)
+	ws, err := gs.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("WebSocket upgrade error: %v", err)
 		return
 	}
-	// nolint:errcheck
-	defer conn.Close()
 
 	// Create player
 	playerID := getPlayerIDFromContext(r.Context())
+	player := sticks.NewPlayer(playerID, "Player")
+	conn := newConnection(ws, player)
+	defer conn.close()
+
 	if playerID == "" {
-		gs.sendError(conn, "Player ID not found")
+		sendError(conn.client, "", newError(ErrorCodeInternal, "player ID not found"))
 		return
 	}
-	player := sticks.NewPlayer(playerID, "Player")
-
-	// Set up WebSocket connection for the player
-	// (You'll need to add a SetConnection method to your Player struct)
 
 	log.Printf("Player %s connected", playerID)
 
-	// Request game from matchmaking
-	gameReady := make(chan *sticks.Game, 1)
-	go func() {
-		game, err := gs.broker.RequestGame(player)
-		if err != nil {
-			log.Printf("Matchmaking error for player %s: %v", playerID, err)
-			gs.sendError(conn, err.Error())
+	if token := r.URL.Query().Get("resume"); token != "" {
+		gs.handleResume(conn, token)
+		return
+	}
+
+	if puzzleID := r.URL.Query().Get("puzzle"); puzzleID != "" {
+		gs.handlePuzzle(conn, puzzleID)
+		return
+	}
+
+	// Request game from matchmaking, or against a bot if one was asked for.
+	// Free for all games have two seats unless players asks for more.
+	difficulty := r.URL.Query().Get("bot")
+	mode := r.URL.Query().Get("mode")
+	players := 0
+	if seats := r.URL.Query().Get("players"); seats != "" {
+		if players, err = strconv.Atoi(seats); err != nil {
+			sendError(conn.client, "", newError(ErrorCodeBadMessage, "invalid number of players %q", seats))
 			return
 		}
-		gameReady <- game
-	}()
+	}
+	queue := sticks.QueueOptions{
+		Region: r.URL.Query().Get("region"),
+		OnStatus: func(status sticks.QueueStatus) {
+			send(conn.client, "", MessageTypeQueueStatus, map[string]any{
+				"position":        status.Position,
+				"waiting":         status.Waiting,
+				"estimatedWaitMs": status.EstimatedWait.Milliseconds(),
+			})
+		},
+	}
+	game, err := gs.waitForMatch(conn, func(ctx context.Context) (*sticks.Game, error) {
+		switch {
+		case difficulty != "":
+			return gs.requestBotGame(player, sticks.BotDifficulty(difficulty))
+		case mode == "teams":
+			return gs.broker.RequestTeamGame(ctx, player, queue)
+		case players > 0:
+			return gs.broker.RequestPartyGame(ctx, player, players, queue)
+		default:
+			return gs.broker.RequestGame(ctx, player, queue)
+		}
+	})
+	switch {
+	case errors.Is(err, context.Canceled):
+		log.Printf("Player %s left the queue", playerID)
+		return
+	case errors.Is(err, sticks.ErrMatchmakingTimeout):
+		sendError(conn.client, "", newError(ErrorCodeTimeout, "matchmaking timed out"))
+		return
+	case err != nil:
+		log.Printf("Matchmaking error for player %s: %v", playerID, err)
+		sendError(conn.client, "", newError(ErrorCodeMatchmaking, "%v", err))
+		return
+	}
 
 	// Handle the game lifecycle
-	select {
-	case game := <-gameReady:
-		gs.handleGameSession(conn, player, game)
-	case <-time.After(30 * time.Second):
-		gs.sendError(conn, "Matchmaking timeout")
+	gs.handleGameSession(conn, game)
+}
+
+// waitForMatch runs request while answering the player's messages, cancelling
+// it if their connection drops so they are not matched as a ghost
+func (gs *GameServer) waitForMatch(conn *connection, request func(context.Context) (*sticks.Game, error)) (*sticks.Game, error) {
+	ctx, cancel := context.WithCancel(conn.ctx)
+	defer cancel()
+
+	type result struct {
+		game *sticks.Game
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		game, err := request(ctx)
+		done <- result{game: game, err: err}
+	}()
+
+	received := conn.received
+	for {
+		select {
+		case r := <-done:
+			return r.game, r.err
+		case r, ok := <-received:
+			var perr *Error
+			switch {
+			case !ok:
+				received = nil
+				cancel()
+			case errors.As(r.err, &perr):
+				sendError(conn.client, "", r.err)
+			case r.err != nil:
+				// the connection error comes next, as the channel closes
+			case r.msg.Type == MessageTypePing:
+				send(conn.client, r.msg.ID, MessageTypePong, nil)
+			default:
+				sendError(conn.client, r.msg.ID, newError(ErrorCodeUnavailable, "%s is not available while waiting for a match", r.msg.Type))
+			}
+		}
+	}
+}
+
+// handleGameSession manages a player's game session. Updates from every
+// player in the game reach this connection through the game's hub.
+func (gs *GameServer) handleGameSession(conn *connection, game *sticks.Game) {
+	hub := gs.hub(game)
+	token := gs.issueToken(hub, conn.player)
+	hub.join(conn)
+	defer gs.leaveHub(hub, conn)
+
+	gs.playInHub(hub, conn, token)
+}
+
+// handleResume puts a player back into the game a resume token was issued
+// for, in the seat they dropped out of
+func (gs *GameServer) handleResume(conn *connection, token string) {
+	hub, player, err := gs.resume(token)
+	if err != nil {
+		sendError(conn.client, "", err)
 		return
 	}
+	log.Printf("Player %s resumed game %s", player.ID, hub.game.ID)
+
+	conn.player = player
+	hub.join(conn)
+	defer gs.leaveHub(hub, conn)
+
+	gs.playInHub(hub, conn, token)
 }
 
-// handleGameSession manages a player's game session
-func (gs *GameServer) handleGameSession(conn *websocket.Conn, player *sticks.Player, game *sticks.Game) {
-	// Notify player that game was found
-	gs.sendMessage(conn, "game_matched", map[string]any{
-		"gameId": game.ID,
-		"player": player,
+// playInHub syncs the player with the game, then handles their messages until
+// they leave or the hub ends the game
+func (gs *GameServer) playInHub(hub *gameHub, conn *connection, token string) {
+	// Notify player that game was found, with the token to resume it
+	send(conn.client, "", MessageTypeGameMatched, map[string]any{
+		"gameId":      hub.game.ID,
+		"seat":        hub.game.PlayerIndex(conn.player.ID),
+		"resumeToken": token,
 	})
 
 	// Send initial game state
-	gs.sendGameState(conn, game)
+	send(conn.client, "", MessageTypeGameState, hub.game.Snapshot())
 
-	// Handle game messages
 	for {
-		var msg Message
-		if err := conn.ReadJSON(&msg); err != nil {
-			log.Printf("WebSocket read error: %v", err)
-			break
+		msg, err := conn.read()
+		if err != nil {
+			var perr *Error
+			if errors.As(err, &perr) {
+				sendError(conn.client, "", err)
+				continue
+			}
+			if conn.ctx.Err() == nil {
+				log.Printf("WebSocket read error: %v", err)
+			}
+			return
 		}
 
-		// Process game actions
-		if err := gs.processGameAction(game, player, msg); err != nil {
-			gs.sendError(conn, err.Error())
-			continue
+		if err := hub.handle(conn, gs.analyzer, gs.broker, msg); err != nil {
+			sendError(conn.client, msg.ID, err)
 		}
+	}
+}
 
-		// Send updated game state
-		gs.sendGameState(conn, game)
-
-		// Check if game is finished
-		if game.State == sticks.GameStateFinished {
-			gs.sendMessage(conn, "game_end", map[string]any{
-				"winner": game.Winner,
-			})
-			break
-		}
+// hint ranks the player's moves. Hints are only given in practice games, on
+// the player's own turn.
+func hint(c *connection, analyzer *sticks.Analyzer, game *sticks.Game) ([]sticks.MoveAnalysis, error) {
+	if !game.Practice {
+		return nil, newError(ErrorCodeUnavailable, "hints are only available in practice games")
 	}
+	if current := game.GetCurrentPlayer(); current == nil || current.ID != c.player.ID {
+		return nil, newError(ErrorCodeNotYourTurn, "not your turn")
+	}
+	return analyzer.Analyze(game)
 }
 
-// processGameAction processes a game action from a player
-func (gs *GameServer) processGameAction(game *sticks.Game, player *sticks.Player, msg Message) error {
-	actionType := msg.Type
+// requestBotGame starts a game against a built in bot
+func (gs *GameServer) requestBotGame(player *sticks.Player, difficulty sticks.BotDifficulty) (*sticks.Game, error) {
+	bot, err := sticks.NewBot(difficulty, gs.broker.BotRules())
+	if err != nil {
+		return nil, err
+	}
+	return gs.broker.RequestBotGame(player, bot)
+}
 
+// processGameAction plays a decoded action for a player
+func processGameAction(game *sticks.Game, player *sticks.Player, payload any) error {
+	if game.IsOver() {
+		return newError(ErrorCodeGameOver, "game is over")
+	}
+	// Resigning, draws and aborting do not wait for the player's turn
+	if err := applyAction(game, player, payload); err != errNotAction {
+		return err
+	}
 	// Verify it's the player's turn
 	currentPlayer := game.GetCurrentPlayer()
-	if currentPlayer.ID != player.ID {
-		return fmt.Errorf("not your turn")
+	if currentPlayer == nil || currentPlayer.ID != player.ID {
+		return newError(ErrorCodeNotYourTurn, "not your turn")
 	}
 
-	switch actionType {
-	case MessageTypeAttack:
-		var data AttackMessageData
-		var ok bool
-		if data, ok = msg.Data.(AttackMessageData); !ok {
-			return fmt.Errorf("invalid action data")
-		}
-		attackerIsLeft := data.WithLeft
-		defenderIsLeft := data.AttackLeft
-
-		return game.Attack(attackerIsLeft, defenderIsLeft)
-
-	case MessageTypeSplit:
-		var data SplitMessageData
-		var ok bool
-		if data, ok = msg.Data.(SplitMessageData); !ok {
-			return fmt.Errorf("invalid action data")
-		}
-		fromLeft := data.WithLeft
-		points := data.Points
-
-		return game.Split(fromLeft, points)
+	move, ok, err := moveFromPayload(game, payload)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return newError(ErrorCodeUnknownType, "not a game action")
+	}
+	if err := game.Apply(move); errors.Is(err, sticks.ErrFlagged) {
+		return newError(ErrorCodeGameOver, "%v", err)
+	} else if err != nil {
+		return newError(ErrorCodeIllegalMove, "%v", err)
+	}
+	return nil
+}
 
+// errNotAction is returned by applyAction for payloads that are moves
+var errNotAction = errors.New("not an action")
+
+// applyAction resigns, aborts or votes on a draw for player
+func applyAction(game *sticks.Game, player *sticks.Player, payload any) error {
+	var err error
+	switch payload.(type) {
+	case *ResignMessageData:
+		err = game.Resign(player.ID)
+	case *AbortMessageData:
+		err = game.Abort(player.ID)
+	case *DrawOfferMessageData:
+		err = game.OfferDraw(player.ID)
+	case *DrawAcceptMessageData:
+		err = game.AcceptDraw(player.ID)
+	case *DrawDeclineMessageData:
+		err = game.DeclineDraw(player.ID)
 	default:
-		return fmt.Errorf("unknown action type: %s", actionType)
+		return errNotAction
 	}
+	if err != nil {
+		return newError(ErrorCodeNotAllowed, "%v", err)
+	}
+	return nil
 }
 
 // handleStats provides server statistics
@@ -222,6 +408,16 @@ func (gs *GameServer) handleStats(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(stats)
 }
 
+// handlePlayerProfile serves a player's rating and rated record. Players
+// without rated games get the starting rating.
+func (gs *GameServer) handlePlayerProfile(w http.ResponseWriter, r *http.Request) {
+	profile := gs.broker.Ratings().Get(r.PathValue("id"))
+
+	w.Header().Set("Content-Type", "application/json")
+	// nolint:errcheck
+	json.NewEncoder(w).Encode(profile)
+}
+
 // Global variables for tracking
 var startTime = time.Now()
 
@@ -239,23 +435,21 @@ func (gs *GameServer) handleHealth(w http.ResponseWriter, r *http.Request) {
 
 // Helper methods
 
-func (gs *GameServer) sendMessage(conn *websocket.Conn, msgType string, data any) {
-	msg := map[string]interface{}{
-		"type": msgType,
-		"data": data,
+// send writes a message to client, replying to the request with id if it is
+// not empty. nil data leaves the data out.
+func send(client websocket.Client, id string, msgType MessageType, data any) {
+	payload, err := encode(id, msgType, data)
+	if err != nil {
+		log.Printf("Error encoding %s message: %v", msgType, err)
+		return
 	}
-
-	if err := conn.WriteJSON(msg); err != nil {
+	if _, err := client.Write(payload); err != nil {
 		log.Printf("Error sending message: %v", err)
 	}
 }
 
-func (gs *GameServer) sendError(conn *websocket.Conn, errorMsg string) {
-	gs.sendMessage(conn, "error", errorMsg)
-}
-
-func (gs *GameServer) sendGameState(conn *websocket.Conn, game *sticks.Game) {
-	gs.sendMessage(conn, "game_state", game)
+func sendError(client websocket.Client, id string, err error) {
+	send(client, id, MessageTypeError, errorData(err))
 }
 
 func generatePlayerID() string {
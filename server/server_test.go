@@ -0,0 +1,176 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	gwebsocket "github.com/gorilla/websocket"
+	"github.com/tkahng/sticks"
+)
+
+// testCookieKey signs the player ID cookies of test players
+var testCookieKey = []byte("test cookie key")
+
+// newTestServer starts a game server for the test and returns its URL
+func newTestServer(t *testing.T, options ...Option) string {
+	t.Helper()
+	gs := NewGameServer(10, append([]Option{WithCookieKey(testCookieKey)}, options...)...)
+	gs.Start()
+	t.Cleanup(gs.Stop)
+	ts := httptest.NewServer(gs.Hanlder())
+	t.Cleanup(ts.Close)
+	return ts.URL
+}
+
+// queueStatus is the data of a queue_status message
+type queueStatus struct {
+	Position int `json:"position"`
+	Waiting  int `json:"waiting"`
+}
+
+// expectQueueStatus reads queue updates until one with waiting players
+// arrives
+func expectQueueStatus(t *testing.T, conn *gwebsocket.Conn, waiting int) queueStatus {
+	t.Helper()
+	for {
+		var status queueStatus
+		if err := json.Unmarshal(expectMessage(t, conn, MessageTypeQueueStatus).Data, &status); err != nil {
+			t.Fatalf("json.Unmarshal() error = %v", err)
+		}
+		if status.Waiting == waiting {
+			return status
+		}
+	}
+}
+
+func TestGameServer_LeavingTheQueue(t *testing.T) {
+	url := newTestServer(t)
+
+	// team games wait for four players, long enough to watch the queue
+	ghost := dialPlayer(t, url, "ghost", "?mode=teams")
+	if status := expectQueueStatus(t, ghost, 1); status.Position != 1 {
+		t.Errorf("queue position = %d, want 1", status.Position)
+	}
+	if err := ghost.WriteJSON(Message{Type: MessageTypePing, ID: "ping", Data: nil}); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	if pong := expectMessage(t, ghost, MessageTypePong); pong.ID != "ping" {
+		t.Errorf("pong id = %q, want ping", pong.ID)
+	}
+
+	alice := dialPlayer(t, url, "alice", "?mode=teams")
+	if status := expectQueueStatus(t, alice, 2); status.Position != 2 {
+		t.Errorf("queue position = %d, want 2", status.Position)
+	}
+
+	// closing the connection takes the player out of the queue
+	_ = ghost.Close()
+	if status := expectQueueStatus(t, alice, 1); status.Position != 1 {
+		t.Errorf("queue position after the player ahead left = %d, want 1", status.Position)
+	}
+}
+
+func TestGameServer_PlayerProfile(t *testing.T) {
+	url, seats, _ := startGame(t)
+
+	if err := seats[1].WriteJSON(Message{Type: MessageTypeResign, ID: "", Data: nil}); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	expectMessage(t, seats[0], MessageTypeGameEnd)
+
+	// the broker rates the game shortly after it ends
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var profile sticks.PlayerRating
+		resp, err := http.Get(url + "/api/players/alice")
+		if err != nil {
+			t.Fatalf("http.Get() error = %v", err)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&profile)
+		_ = resp.Body.Close()
+		if err != nil {
+			t.Fatalf("decoding profile: %v", err)
+		}
+		if profile.Games == 1 {
+			if profile.PlayerID != "alice" || profile.Value == 1500 {
+				t.Errorf("profile = %+v, want alice's rating moved by the game", profile)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("profile = %+v, want one rated game", profile)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestGameServer_BrokerOptions(t *testing.T) {
+	backfill := sticks.NewBotBackfill(sticks.NewFIFOMatchmaker(), 0, sticks.BotDifficultyEasy)
+	url := newTestServer(t, WithBrokerOptions(sticks.WithMatchmaker(backfill)))
+
+	// a lone player gets a bot straight away
+	conn := dialPlayer(t, url, "alice", "?region=eu")
+	expectMessage(t, conn, MessageTypeGameMatched)
+	var snapshot sticks.GameSnapshot
+	if err := json.Unmarshal(expectMessage(t, conn, MessageTypeGameState).Data, &snapshot); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if len(snapshot.Players) != 2 || snapshot.Players[0].ID != "alice" {
+		t.Errorf("players = %+v, want alice against a bot", snapshot.Players)
+	}
+}
+
+func TestGameServer_PartyGame(t *testing.T) {
+	url := newTestServer(t)
+
+	conns := []*gwebsocket.Conn{
+		dialPlayer(t, url, "alice", "?players=3"),
+		dialPlayer(t, url, "bob", "?players=3"),
+		dialPlayer(t, url, "carol", "?players=3"),
+	}
+	for i, conn := range conns {
+		expectMessage(t, conn, MessageTypeGameMatched)
+		var snapshot sticks.GameSnapshot
+		if err := json.Unmarshal(expectMessage(t, conn, MessageTypeGameState).Data, &snapshot); err != nil {
+			t.Fatalf("json.Unmarshal() error = %v", err)
+		}
+		if len(snapshot.Players) != 3 {
+			t.Errorf("player %d's game has %d players, want 3", i+1, len(snapshot.Players))
+		}
+	}
+}
+
+func TestPlayerID_SignedCookie(t *testing.T) {
+	handler := PlayerID(testCookieKey, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(getPlayerIDFromContext(r.Context())))
+	}))
+	request := func(cookie string) (string, *http.Cookie) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if cookie != "" {
+			r.Header.Set("Cookie", playerIDCookie+"="+cookie)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		cookies := w.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Name != playerIDCookie {
+			t.Fatalf("response cookies = %v, want one %s cookie", cookies, playerIDCookie)
+		}
+		return w.Body.String(), cookies[0]
+	}
+
+	issued, cookie := request("")
+	if issued == "" {
+		t.Fatalf("no player ID issued")
+	}
+	if again, _ := request(cookie.Value); again != issued {
+		t.Errorf("player ID from the issued cookie = %q, want %q", again, issued)
+	}
+	for _, forged := range []string{"alice", "alice.c2lnbmF0dXJl", signPlayerID([]byte("other key"), "alice")} {
+		if got, _ := request(forged); got == "alice" {
+			t.Errorf("cookie %q claimed alice's ID", forged)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package sticks
+
+import "time"
+
+// SnapshotVersion is the version of the GameSnapshot schema. Bump it whenever
+// a field changes meaning or is removed.
+const SnapshotVersion = 1
+
+// GameSnapshot is a point in time copy of a game, safe to marshal and hand to
+// clients while the game keeps changing
+type GameSnapshot struct {
+	Version     int              `json:"version"`
+	ID          string           `json:"id"`
+	State       GameState        `json:"state"`
+	Rules       Ruleset          `json:"rules"`
+	Players     []PlayerSnapshot `json:"players"`          // in turn order
+	CurrentTurn int              `json:"currentTurn"`      // index into Players
+	Winner      string           `json:"winner,omitempty"` // ID of the winning player
+	Result      ResultReason     `json:"result,omitempty"`
+	Clocks      []ClockSnapshot  `json:"clocks,omitempty"` // indexed by turn, empty for untimed games
+	MoveCount   int              `json:"moveCount"`
+	// DrawOfferedBy is the ID of the player offering a draw, empty for none
+	DrawOfferedBy string    `json:"drawOfferedBy,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+	TakenAt       time.Time `json:"takenAt"`
+}
+
+// PlayerSnapshot is a player's seat and hands in a GameSnapshot
+type PlayerSnapshot struct {
+	ID        string       `json:"id"`
+	Name      string       `json:"name"`
+	Team      int          `json:"team"`
+	Rating    int          `json:"rating,omitempty"` // rating when the game started, 0 if unrated
+	Alive     bool         `json:"alive"`
+	LeftHand  HandSnapshot `json:"leftHand"`
+	RightHand HandSnapshot `json:"rightHand"`
+}
+
+// HandSnapshot is a hand in a GameSnapshot
+type HandSnapshot struct {
+	Points int  `json:"points"`
+	Alive  bool `json:"alive"`
+}
+
+// ClockSnapshot is a player's clock in a GameSnapshot
+type ClockSnapshot struct {
+	RemainingMs int64 `json:"remainingMs"` // counts the turn in progress
+	Running     bool  `json:"running"`
+}
+
+// Snapshot returns a copy of the game's current state
+func (g *Game) Snapshot() GameSnapshot {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+	return g.snapshot(time.Now())
+}
+
+// snapshot copies the game's state at now without locking
+func (g *Game) snapshot(now time.Time) GameSnapshot {
+	players := make([]PlayerSnapshot, len(g.Players))
+	for i, p := range g.Players {
+		players[i] = PlayerSnapshot{
+			ID:        p.ID,
+			Name:      p.Name,
+			Team:      p.Team,
+			Rating:    p.Rating,
+			Alive:     p.Alive(),
+			LeftHand:  snapshotHand(p.LeftHand),
+			RightHand: snapshotHand(p.RightHand),
+		}
+	}
+
+	var clocks []ClockSnapshot
+	for i, c := range g.Clocks {
+		clocks = append(clocks, ClockSnapshot{
+			RemainingMs: max(g.remaining(i, now), 0).Milliseconds(),
+			Running:     c.Running,
+		})
+	}
+
+	winner := ""
+	if g.Winner != nil {
+		winner = g.Winner.ID
+	}
+
+	return GameSnapshot{
+		Version:       SnapshotVersion,
+		ID:            g.ID,
+		State:         g.State,
+		Rules:         g.Rules,
+		Players:       players,
+		CurrentTurn:   g.CurrentTurn,
+		Winner:        winner,
+		Result:        g.Result,
+		Clocks:        clocks,
+		MoveCount:     len(g.History),
+		DrawOfferedBy: g.DrawOfferedBy,
+		CreatedAt:     g.CreatedAt,
+		TakenAt:       now,
+	}
+}
+
+func snapshotHand(h *Hand) HandSnapshot {
+	return HandSnapshot{
+		Points: h.fingers,
+		Alive:  h.Alive(),
+	}
+}
+
+// Position returns the position the snapshot was taken at
+func (s GameSnapshot) Position() Position {
+	p := Position{
+		Hands:   [MaxPlayers][2]uint8{},
+		Players: uint8(len(s.Players)),
+		Turn:    uint8(s.CurrentTurn),
+	}
+	for i, player := range s.Players {
+		p.Hands[i] = [2]uint8{uint8(player.LeftHand.Points), uint8(player.RightHand.Points)}
+	}
+	return p
+}
@@ -0,0 +1,78 @@
+package sticks
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGame_Snapshot(t *testing.T) {
+	game := NewGame("game", DefaultRuleset())
+	game.TimeControl = &TimeControl{Base: time.Minute, Increment: 0, PerMove: 0}
+	if err := game.AddPlayer(NewPlayer("player 1", "Alice")); err != nil {
+		t.Fatalf("Game.AddPlayer() error = %v", err)
+	}
+	if err := game.AddPlayer(NewPlayer("player 2", "Bob")); err != nil {
+		t.Fatalf("Game.AddPlayer() error = %v", err)
+	}
+	if err := game.StartGame(); err != nil {
+		t.Fatalf("Game.StartGame() error = %v", err)
+	}
+	if err := game.Attack(true, true); err != nil {
+		t.Fatalf("Game.Attack() error = %v", err)
+	}
+
+	snapshot := game.Snapshot()
+	if snapshot.Version != SnapshotVersion || snapshot.MoveCount != 1 || snapshot.CurrentTurn != 1 {
+		t.Errorf("Game.Snapshot() = %+v", snapshot)
+	}
+	if got := snapshot.Players[1].LeftHand; got != (HandSnapshot{Points: 2, Alive: true}) {
+		t.Errorf("player 2 left hand = %+v, want 2 points and alive", got)
+	}
+	if len(snapshot.Clocks) != 2 || !snapshot.Clocks[1].Running || snapshot.Clocks[1].RemainingMs <= 0 {
+		t.Errorf("Game.Snapshot() clocks = %+v", snapshot.Clocks)
+	}
+
+	if got, want := snapshot.Position(), game.Position(); got != want {
+		t.Errorf("GameSnapshot.Position() = %v, want %v", got, want)
+	}
+
+	// later moves leave the snapshot alone
+	if err := game.Attack(true, true); err != nil {
+		t.Fatalf("Game.Attack() error = %v", err)
+	}
+	if snapshot.MoveCount != 1 || snapshot.Players[0].LeftHand.Points != 1 {
+		t.Errorf("Game.Snapshot() changed after a move: %+v", snapshot)
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if !strings.Contains(string(data), `"leftHand":{"points":1,"alive":true}`) {
+		t.Errorf("json.Marshal() = %s", data)
+	}
+}
+
+func TestGame_SnapshotWhileMoving(t *testing.T) {
+	game := newStartedGame(t, DefaultRuleset())
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100 && !game.IsOver(); i++ {
+			if moves := game.LegalMoves(); len(moves) > 0 {
+				_ = game.Apply(moves[0])
+			}
+		}
+	}()
+	for range 100 {
+		if _, err := json.Marshal(game.Snapshot()); err != nil {
+			t.Fatalf("json.Marshal() error = %v", err)
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,355 @@
+package sticks
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Outcome is the result of a position for the player to move under perfect play
+type Outcome uint8
+
+const (
+	OutcomeUnknown Outcome = iota // position is unreachable from the start
+	OutcomeWin
+	OutcomeLoss
+	OutcomeDraw
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case OutcomeWin:
+		return "win"
+	case OutcomeLoss:
+		return "loss"
+	case OutcomeDraw:
+		return "draw"
+	default:
+		return "unknown"
+	}
+}
+
+func (o Outcome) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
+}
+
+func (o *Outcome) UnmarshalText(text []byte) error {
+	for _, candidate := range []Outcome{OutcomeUnknown, OutcomeWin, OutcomeLoss, OutcomeDraw} {
+		if candidate.String() == string(text) {
+			*o = candidate
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown outcome %q", text)
+}
+
+// TablebaseEntry is the solved value of a single position
+type TablebaseEntry struct {
+	Outcome  Outcome `json:"outcome"`
+	Distance int     `json:"distance"` // plies until the game ends; 0 for draws
+}
+
+// Tablebase holds the perfect-play value of every position reachable from the
+// start of a two player game under a ruleset
+type Tablebase struct {
+	Rules   Ruleset
+	entries []TablebaseEntry
+}
+
+const (
+	tablebaseMagic   = "STKT"
+	tablebaseVersion = 1
+	// maxTablebaseEntries caps the table at a few megabytes on disk
+	maxTablebaseEntries = 1 << 22
+	// entries pack the outcome into the top bits and the distance below it
+	tablebaseOutcomeShift = 14
+	maxTablebaseDistance  = 1<<tablebaseOutcomeShift - 1
+)
+
+// index returns the position's slot in a table for hands with the given limit
+func (p Position) index(fingers int) int {
+	i := 0
+	for _, hands := range p.Hands[:p.Players] {
+		for _, f := range hands {
+			i = i*fingers + int(f)
+		}
+	}
+	return i*int(p.Players) + int(p.Turn)
+}
+
+// positionAt is the inverse of Position.index for a table of players
+func positionAt(idx, players, fingers int) Position {
+	p := Position{Hands: [MaxPlayers][2]uint8{}, Players: uint8(players), Turn: uint8(idx % players)}
+	idx /= players
+	for seat := players - 1; seat >= 0; seat-- {
+		for hand := 1; hand >= 0; hand-- {
+			p.Hands[seat][hand] = uint8(idx % fingers)
+			idx /= fingers
+		}
+	}
+	return p
+}
+
+// lost reports whether the player to move has no living hands
+func (p Position) lost(rules Ruleset) bool {
+	hands := p.Hands[p.Turn]
+	return !rules.alive(int(hands[0])) && !rules.alive(int(hands[1]))
+}
+
+// Solve enumerates every position reachable from the start of a game under
+// rules and labels it by retrograde analysis
+func Solve(rules Ruleset) (*Tablebase, error) {
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+	if rules.Players != 2 {
+		return nil, fmt.Errorf("only two player games can be solved")
+	}
+	size := 2
+	for range 4 {
+		size *= rules.Fingers
+		if size > maxTablebaseEntries {
+			return nil, fmt.Errorf("%d fingers per hand is too many positions to solve", rules.Fingers)
+		}
+	}
+
+	// Walk forward from the start to find every reachable position and its
+	// successors
+	start := startingPosition(rules)
+	successors := map[int][]int{}
+	reachable := []Position{start}
+	seen := map[int]bool{start.index(rules.Fingers): true}
+	for i := 0; i < len(reachable); i++ {
+		p := reachable[i]
+		if p.lost(rules) {
+			continue
+		}
+
+		g, err := p.Game(rules)
+		if err != nil {
+			return nil, err
+		}
+		var next []int
+		for _, m := range g.legalMoves() {
+			child, err := p.Game(rules)
+			if err != nil {
+				return nil, err
+			}
+			if err := child.apply(m); err != nil {
+				return nil, err
+			}
+			q := child.position()
+			q.Turn = 1 - p.Turn
+
+			idx := q.index(rules.Fingers)
+			next = append(next, idx)
+			if !seen[idx] {
+				seen[idx] = true
+				reachable = append(reachable, q)
+			}
+		}
+		successors[p.index(rules.Fingers)] = next
+	}
+
+	// Work backwards from the finished positions. A position is won as soon
+	// as one move reaches a lost position and lost once every move reaches a
+	// won one. Labelling in rounds keeps the distances to the end exact.
+	entries := make([]TablebaseEntry, size)
+	for _, p := range reachable {
+		entry := TablebaseEntry{Outcome: OutcomeDraw, Distance: 0}
+		if p.lost(rules) {
+			entry.Outcome = OutcomeLoss
+		}
+		entries[p.index(rules.Fingers)] = entry
+	}
+	resolved := func(e TablebaseEntry) bool {
+		return e.Outcome == OutcomeWin || e.Outcome == OutcomeLoss
+	}
+
+	for changed := true; changed; {
+		changed = false
+		next := append([]TablebaseEntry(nil), entries...)
+		for idx, succ := range successors {
+			if resolved(entries[idx]) {
+				continue
+			}
+
+			win, loss := -1, 0
+			allWin := true
+			for _, s := range succ {
+				switch entries[s].Outcome {
+				case OutcomeLoss:
+					if win < 0 || entries[s].Distance+1 < win {
+						win = entries[s].Distance + 1
+					}
+				case OutcomeWin:
+					loss = max(loss, entries[s].Distance+1)
+				default:
+					allWin = false
+				}
+			}
+
+			switch {
+			case win >= 0:
+				next[idx] = TablebaseEntry{Outcome: OutcomeWin, Distance: win}
+			case allWin:
+				next[idx] = TablebaseEntry{Outcome: OutcomeLoss, Distance: loss}
+			default:
+				continue
+			}
+			if next[idx].Distance > maxTablebaseDistance {
+				return nil, fmt.Errorf("distance %d is too long to store", next[idx].Distance)
+			}
+			changed = true
+		}
+		entries = next
+	}
+
+	return &Tablebase{Rules: rules, entries: entries}, nil
+}
+
+// Lookup returns the value of a two player game's current position for the
+// player to move
+func (tb *Tablebase) Lookup(g *Game) (TablebaseEntry, bool) {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+
+	if g.Rules != tb.Rules || len(g.Players) != 2 {
+		return TablebaseEntry{}, false
+	}
+	return tb.probe(g.position())
+}
+
+// probe returns the value of a position for the player to move
+func (tb *Tablebase) probe(p Position) (TablebaseEntry, bool) {
+	if p.Players != 2 {
+		return TablebaseEntry{}, false
+	}
+	for _, hands := range p.Hands[:p.Players] {
+		for _, f := range hands {
+			if int(f) >= tb.Rules.Fingers {
+				return TablebaseEntry{}, false
+			}
+		}
+	}
+	entry := tb.entries[p.index(tb.Rules.Fingers)]
+	return entry, entry.Outcome != OutcomeUnknown
+}
+
+// Len returns the number of reachable positions in the tablebase
+func (tb *Tablebase) Len() int {
+	n := 0
+	for _, e := range tb.entries {
+		if e.Outcome != OutcomeUnknown {
+			n++
+		}
+	}
+	return n
+}
+
+// WriteTo saves the tablebase in its compact binary form
+func (tb *Tablebase) WriteTo(w io.Writer) (int64, error) {
+	rules, err := json.Marshal(tb.Rules)
+	if err != nil {
+		return 0, err
+	}
+
+	bw := bufio.NewWriter(w)
+	cw := &countingWriter{w: bw, n: 0}
+	header := []any{
+		[]byte(tablebaseMagic),
+		uint8(tablebaseVersion),
+		uint16(len(rules)),
+		rules,
+		uint32(len(tb.entries)),
+	}
+	for _, v := range header {
+		if err := binary.Write(cw, binary.LittleEndian, v); err != nil {
+			return cw.n, err
+		}
+	}
+	packed := make([]uint16, len(tb.entries))
+	for i, e := range tb.entries {
+		packed[i] = uint16(e.Outcome)<<tablebaseOutcomeShift | uint16(e.Distance)
+	}
+	if err := binary.Write(cw, binary.LittleEndian, packed); err != nil {
+		return cw.n, err
+	}
+	return cw.n, bw.Flush()
+}
+
+// ReadTablebase loads a tablebase saved by WriteTo
+func ReadTablebase(r io.Reader) (*Tablebase, error) {
+	br := bufio.NewReader(r)
+
+	magic := make([]byte, len(tablebaseMagic))
+	if _, err := io.ReadFull(br, magic); err != nil {
+		return nil, err
+	}
+	if string(magic) != tablebaseMagic {
+		return nil, fmt.Errorf("not a tablebase file")
+	}
+
+	var version uint8
+	if err := binary.Read(br, binary.LittleEndian, &version); err != nil {
+		return nil, err
+	}
+	if version != tablebaseVersion {
+		return nil, fmt.Errorf("unsupported tablebase version %d", version)
+	}
+
+	var rulesLen uint16
+	if err := binary.Read(br, binary.LittleEndian, &rulesLen); err != nil {
+		return nil, err
+	}
+	rulesJSON := make([]byte, rulesLen)
+	if _, err := io.ReadFull(br, rulesJSON); err != nil {
+		return nil, err
+	}
+	var rules Ruleset
+	if err := json.Unmarshal(rulesJSON, &rules); err != nil {
+		return nil, fmt.Errorf("invalid tablebase ruleset: %w", err)
+	}
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+
+	var count uint32
+	if err := binary.Read(br, binary.LittleEndian, &count); err != nil {
+		return nil, err
+	}
+	if count > maxTablebaseEntries {
+		return nil, fmt.Errorf("tablebase has too many entries: %d", count)
+	}
+	if want := 2 * rules.Fingers * rules.Fingers * rules.Fingers * rules.Fingers; int(count) != want {
+		return nil, fmt.Errorf("tablebase has %d entries, want %d", count, want)
+	}
+
+	packed := make([]uint16, count)
+	if err := binary.Read(br, binary.LittleEndian, packed); err != nil {
+		return nil, err
+	}
+	entries := make([]TablebaseEntry, count)
+	for i, v := range packed {
+		entries[i] = TablebaseEntry{
+			Outcome:  Outcome(v >> tablebaseOutcomeShift),
+			Distance: int(v & maxTablebaseDistance),
+		}
+	}
+
+	return &Tablebase{Rules: rules, entries: entries}, nil
+}
+
+// countingWriter tracks how many bytes have been written for io.WriterTo
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,105 @@
+package sticks
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSolve(t *testing.T) {
+	rollover := DefaultRuleset()
+	rollover.Variant = RuleVariantRollover
+
+	for _, rules := range []Ruleset{DefaultRuleset(), rollover} {
+		t.Run(string(rules.Variant), func(t *testing.T) {
+			tb, err := Solve(rules)
+			if err != nil {
+				t.Fatalf("Solve() error = %v", err)
+			}
+			if tb.Len() == 0 {
+				t.Fatalf("Solve() found no positions")
+			}
+
+			// every labelled position must agree with its successors
+			for idx, entry := range tb.entries {
+				if entry.Outcome == OutcomeUnknown {
+					continue
+				}
+				p := positionAt(idx, 2, rules.Fingers)
+				if p.lost(rules) {
+					if entry.Outcome != OutcomeLoss || entry.Distance != 0 {
+						t.Errorf("finished position %v = %v, want loss in 0", p, entry)
+					}
+					continue
+				}
+
+				best := TablebaseEntry{Outcome: OutcomeUnknown, Distance: 0}
+				g, err := p.Game(rules)
+				if err != nil {
+					t.Fatalf("Position.Game() error = %v", err)
+				}
+				for _, m := range g.legalMoves() {
+					child, _ := p.Game(rules)
+					if err := child.apply(m); err != nil {
+						t.Fatalf("Game.apply(%v) error = %v", m, err)
+					}
+					q := child.position()
+					q.Turn = 1 - p.Turn
+					reply, ok := tb.probe(q)
+					if !ok {
+						t.Fatalf("successor %v of %v is missing", q, p)
+					}
+					switch {
+					case reply.Outcome == OutcomeLoss && (best.Outcome != OutcomeWin || reply.Distance+1 < best.Distance):
+						best = TablebaseEntry{Outcome: OutcomeWin, Distance: reply.Distance + 1}
+					case best.Outcome == OutcomeWin:
+					case reply.Outcome == OutcomeDraw:
+						best = TablebaseEntry{Outcome: OutcomeDraw, Distance: 0}
+					case reply.Outcome == OutcomeWin && best.Outcome != OutcomeDraw && reply.Distance+1 > best.Distance:
+						best = TablebaseEntry{Outcome: OutcomeLoss, Distance: reply.Distance + 1}
+					}
+				}
+				if best != entry {
+					t.Errorf("position %v = %v, want %v", p, entry, best)
+				}
+			}
+		})
+	}
+}
+
+func TestTablebase_WriteRead(t *testing.T) {
+	tb, err := Solve(DefaultRuleset())
+	if err != nil {
+		t.Fatalf("Solve() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	n, err := tb.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("Tablebase.WriteTo() error = %v", err)
+	}
+	if int(n) != buf.Len() {
+		t.Errorf("Tablebase.WriteTo() = %d bytes, wrote %d", n, buf.Len())
+	}
+
+	loaded, err := ReadTablebase(&buf)
+	if err != nil {
+		t.Fatalf("ReadTablebase() error = %v", err)
+	}
+	if loaded.Rules != tb.Rules {
+		t.Errorf("ReadTablebase() rules = %v, want %v", loaded.Rules, tb.Rules)
+	}
+	for i := range tb.entries {
+		if loaded.entries[i] != tb.entries[i] {
+			t.Fatalf("ReadTablebase() entry %d = %v, want %v", i, loaded.entries[i], tb.entries[i])
+		}
+	}
+
+	game := newStartedGame(t, DefaultRuleset())
+	if _, ok := loaded.Lookup(game); !ok {
+		t.Errorf("Tablebase.Lookup() missing the starting position")
+	}
+
+	if _, err := ReadTablebase(bytes.NewReader([]byte("nope"))); err == nil {
+		t.Errorf("ReadTablebase() of garbage should fail")
+	}
+}
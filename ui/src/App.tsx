@@ -3,26 +3,61 @@ import useWebSocket from "react-use-websocket";
 import "./App.css";
 import { ModeToggle } from "./components/mode-toggle";
 import { Providers } from "./components/providers";
+// mirrors server.Message; replies carry the id of the request they answer
 type Message<T> = {
   type: string;
+  id?: string;
   data: T;
 };
-type GameState = "ready" | "in-progress" | "waiting" | "finished";
+type GameState =
+  | "ready"
+  | "in_progress"
+  | "waiting"
+  | "finished"
+  | "draw"
+  | "aborted";
+// mirrors sticks.HandSnapshot
+type Hand = {
+  points: number;
+  alive: boolean;
+};
+// mirrors sticks.PlayerSnapshot
+type Player = {
+  id: string;
+  name: string;
+  team: number;
+  rating?: number; // at the start of the game, left out if unrated
+  alive: boolean;
+  leftHand: Hand;
+  rightHand: Hand;
+};
+// mirrors sticks.GameSnapshot
 type Game = {
-  // 	CurrentTurn int       `json:"currentTurn"` // 0 for player1, 1 for player2
-  currentTurn: number;
+  version: number;
+  id: string;
   state: GameState;
-  // State       GameState `json:"state"`
-  winner: string | null;
-  // Winner      *Player   `json:"winner,omitempty"`
-  createdAt: Date;
-  // CreatedAt   time.Time `json:"createdAt"`
+  players: Player[];
+  currentTurn: number;
+  winner?: string;
+  result?: string;
+  clocks?: { remainingMs: number; running: boolean }[];
+  moveCount: number;
+  drawOfferedBy?: string;
+  createdAt: string;
 };
 type GameMessage = Message<Game>;
 
-type ErrorMessage = Message<string>;
+// mirrors server.ErrorMessageData
+type ErrorMessage = Message<{ code: string; message: string }>;
+
+// queue_status while waiting for a match
+type QueueStatusMessage = Message<{
+  position: number;
+  waiting: number;
+  estimatedWaitMs: number;
+}>;
 
-type IncomingMessage = GameMessage | ErrorMessage;
+type IncomingMessage = GameMessage | ErrorMessage | QueueStatusMessage;
 function App() {
   const [count, setCount] = useState(0);
   const WS_URL = "/api/ws";
@@ -30,7 +65,7 @@ function App() {
   const { lastJsonMessage } = useWebSocket<IncomingMessage | null>(WS_URL);
   const handleMessage = (msg: IncomingMessage | null) => {
     if (!msg) return;
-    if (msg.type === "error") return;
+    if (msg.type === "error" || msg.type === "queue_status") return;
     if (msg.type !== "game_state") {
       const state = msg.data as Game;
       setStats(state);
@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"runtime"
@@ -68,6 +69,10 @@ type Client interface {
 	// SetLogger allows consumers to inject their own logging dependencies
 	SetLogger(any) error
 
+	// SetWriteTimeout sets how long Write waits for room in the client's
+	// queue, and how long a single write to the connection may take
+	SetWriteTimeout(time.Duration)
+
 	// Log allows implementors to use their own logging dependencies
 	Log(int, string, ...any)
 
@@ -123,12 +128,22 @@ func ServeWS(
 	}
 }
 
+// DefaultWriteTimeout is how long a client waits on a peer that is not
+// keeping up before giving up on it
+const DefaultWriteTimeout = 10 * time.Second
+
+// ErrWriteTimeout is returned by Write when the client's queue stays full for
+// the write timeout, which means the peer is not reading
+var ErrWriteTimeout = errors.New("websocket: client is not keeping up")
+
 type client struct {
-	lock   *sync.RWMutex
-	wg     *sync.WaitGroup
-	conn   *websocket.Conn
-	egress chan []byte
-	logger *slog.Logger
+	lock    *sync.RWMutex
+	wg      *sync.WaitGroup
+	conn    *websocket.Conn
+	egress  chan []byte
+	stopped chan struct{} // closed once WriteForever returns
+	timeout time.Duration
+	logger  *slog.Logger
 }
 
 // Conn implements Client.
@@ -143,18 +158,51 @@ func NewClient(c *websocket.Conn) Client {
 	wg := &sync.WaitGroup{}
 	wg.Add(2)
 	return &client{
-		lock:   &sync.RWMutex{},
-		wg:     wg,
-		conn:   c,
-		egress: make(chan []byte, 32),
-		logger: slog.New(slog.NewJSONHandler(os.Stdout, nil)),
+		lock:    &sync.RWMutex{},
+		wg:      wg,
+		conn:    c,
+		egress:  make(chan []byte, 32),
+		stopped: make(chan struct{}),
+		timeout: DefaultWriteTimeout,
+		logger:  slog.New(slog.NewJSONHandler(os.Stdout, nil)),
 	}
 }
 
-// Write implements the Writer interface.
+// Write implements the Writer interface. It queues p for WriteForever,
+// waiting at most the write timeout for room, and fails once the writer has
+// stopped.
 func (c *client) Write(p []byte) (int, error) {
-	c.egress <- p
-	return len(p), nil
+	c.lock.RLock()
+	timeout := c.timeout
+	c.lock.RUnlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case c.egress <- p:
+		return len(p), nil
+	case <-c.stopped:
+		return 0, net.ErrClosed
+	case <-timer.C:
+		return 0, ErrWriteTimeout
+	}
+}
+
+// SetWriteTimeout implements Client.
+func (c *client) SetWriteTimeout(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.timeout = d
+}
+
+// writeMessage writes to the connection, giving up after the write timeout
+func (c *client) writeMessage(messageType int, data []byte) error {
+	c.lock.RLock()
+	timeout := c.timeout
+	c.lock.RUnlock()
+
+	_ = c.conn.SetWriteDeadline(time.Now().Add(timeout))
+	return c.conn.WriteMessage(messageType, data)
 }
 
 // Close implements the Closer interface. Note the behavior of calling Close()
@@ -171,6 +219,7 @@ func (c *client) Close() error {
 func (c *client) WriteForever(ctx context.Context, onDestroy func(Client), ping time.Duration) {
 	pingTicker := time.NewTicker(ping)
 	defer func() {
+		close(c.stopped)
 		c.wg.Done()
 		pingTicker.Stop()
 		onDestroy(c)
@@ -179,21 +228,32 @@ func (c *client) WriteForever(ctx context.Context, onDestroy func(Client), ping
 	for {
 		select {
 		case <-ctx.Done():
-			_ = c.conn.WriteMessage(websocket.CloseMessage, nil)
-			return
+			// flush what was queued before the shutdown, so final messages
+			// still reach the client
+			for {
+				select {
+				case msgBytes := <-c.egress:
+					if err := c.writeMessage(websocket.TextMessage, msgBytes); err != nil {
+						return
+					}
+				default:
+					_ = c.writeMessage(websocket.CloseMessage, nil)
+					return
+				}
+			}
 		case msgBytes, ok := <-c.egress:
 			// ok will be false in case the egress channel is closed
 			if !ok {
-				_ = c.conn.WriteMessage(websocket.CloseMessage, nil)
+				_ = c.writeMessage(websocket.CloseMessage, nil)
 				return
 			}
 			// write a message to the connection
-			if err := c.conn.WriteMessage(websocket.TextMessage, msgBytes); err != nil {
+			if err := c.writeMessage(websocket.TextMessage, msgBytes); err != nil {
 				c.Log(int(slog.LevelError), fmt.Sprintf("error writing message: %v", err))
 				return
 			}
 		case <-pingTicker.C:
-			if err := c.conn.WriteMessage(websocket.PingMessage, []byte{}); err != nil {
+			if err := c.writeMessage(websocket.PingMessage, []byte{}); err != nil {
 				c.Log(int(slog.LevelError), fmt.Sprintf("error writing ping: %v", err))
 				return
 			}
@@ -374,6 +434,7 @@ func (m *manager) Run(ctx context.Context) {
 				cleanupClient(client)
 			}
 			m.mu.Unlock()
+			return
 		case rr := <-m.register:
 			m.mu.Lock()
 			m.clients[rr.client] = rr.cancel